@@ -0,0 +1,234 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/tidwall/buntdb"
+)
+
+// backupFilePrefix and backupFileSuffix surround the timestamp of scheduled snapshots file names
+const (
+	backupFilePrefix = "data-"
+	backupFileSuffix = ".db"
+)
+
+// setupDatabaseMaintenance configures buntdb compaction and starts scheduled snapshots and compactions
+func setupDatabaseMaintenance(db *buntdb.DB) error {
+	if shrinkInterval > 0 {
+		var config buntdb.Config
+		if err := db.ReadConfig(&config); err != nil {
+			return err
+		}
+
+		// Compaction is scheduled, buntdb should not trigger it on its own
+		config.AutoShrinkDisabled = true
+		if err := db.SetConfig(config); err != nil {
+			return err
+		}
+
+		go runPeriodically(shrinkInterval, func() { shrinkDatabase(db) })
+	}
+
+	if backupInterval > 0 {
+		if err := os.MkdirAll(backupDir, 0o755); err != nil {
+			return err
+		}
+
+		go runPeriodically(backupInterval, func() { backupDatabase(db) })
+	}
+
+	log.Info().Dur("backupInterval", backupInterval).Int("backupRetention", backupRetention).
+		Dur("shrinkInterval", shrinkInterval).Msg("Database maintenance scheduled")
+
+	return nil
+}
+
+// runPeriodically runs f every interval as in-flight work, until shutdown
+func runPeriodically(interval time.Duration, f func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-botCtx.Done():
+			return
+		case <-ticker.C:
+			if !startInFlight() {
+				return
+			}
+			f()
+			doneInFlight()
+		}
+	}
+}
+
+// shrinkDatabase compacts database file
+func shrinkDatabase(db *buntdb.DB) {
+	log.Debug().Msg("shrinkDatabase")
+	start := time.Now()
+	if err := db.Shrink(); err != nil && err != buntdb.ErrShrinkInProcess {
+		log.Error().Err(err).Msg("Failed to shrink database")
+		return
+	}
+
+	log.Info().Dur("duration", time.Since(start)).Msg("Database shrunk")
+}
+
+// backupDatabase saves a timestamped snapshot in backupDir and removes the ones exceeding backupRetention
+func backupDatabase(db *buntdb.DB) {
+	log.Debug().Msg("backupDatabase")
+	path := newBackupPath()
+	if err := snapshotDatabase(db, path); err != nil {
+		log.Error().Err(err).Str("path", path).Msg("Failed to backup database")
+		return
+	}
+
+	log.Info().Str("path", path).Msg("Database backup saved")
+
+	if err := pruneBackups(); err != nil {
+		log.Error().Err(err).Msg("Failed to prune database backups")
+	}
+}
+
+// newBackupPath returns a timestamped snapshot path in backupDir
+func newBackupPath() string {
+	return filepath.Join(backupDir, backupFilePrefix+time.Now().Format("20060102-150405")+backupFileSuffix)
+}
+
+// pruneBackups removes the oldest scheduled snapshots, keeping backupRetention of them
+func pruneBackups() error {
+	entries, err := os.ReadDir(backupDir)
+	if err != nil {
+		return err
+	}
+
+	var backups []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() && strings.HasPrefix(name, backupFilePrefix) && strings.HasSuffix(name, backupFileSuffix) {
+			backups = append(backups, name)
+		}
+	}
+
+	if len(backups) <= backupRetention {
+		return nil
+	}
+
+	// Timestamped names sort chronologically
+	sort.Strings(backups)
+	for _, name := range backups[:len(backups)-backupRetention] {
+		if err = os.Remove(filepath.Join(backupDir, name)); err != nil {
+			return err
+		}
+		log.Debug().Str("name", name).Msg("Removed old database backup")
+	}
+
+	return nil
+}
+
+// snapshotDatabase saves a consistent copy of the database to path
+// The copy is written to a temporary file first, path never contains a partial snapshot
+func snapshotDatabase(db *buntdb.DB, path string) error {
+	tmpPath := path + ".tmp"
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+
+	err = db.Save(f)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, path)
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	return nil
+}
+
+// restoreDatabase replaces the data file at dbPath with a snapshot, current data file is kept aside
+// Database must not be opened while restoring
+func restoreDatabase(snapshotPath, dbPath string) (string, error) {
+	// buntdb creates missing files, a mistyped path would otherwise be restored as an empty database
+	info, err := os.Stat(snapshotPath)
+	if err != nil {
+		return "", fmt.Errorf("invalid snapshot: %w", err)
+	}
+	if !info.Mode().IsRegular() || info.Size() == 0 {
+		return "", fmt.Errorf("invalid snapshot: %s is not a database file", snapshotPath)
+	}
+
+	// Never open the snapshot itself, buntdb rewrites its file, validate the copy to be installed instead
+	tmpPath := dbPath + ".restore"
+	if err = copyFile(snapshotPath, tmpPath); err != nil {
+		return "", err
+	}
+
+	version, err := readSnapshotVersion(tmpPath)
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return "", fmt.Errorf("invalid snapshot: %w", err)
+	}
+	log.Info().Str("path", snapshotPath).Int("version", version).Msg("Restoring database snapshot")
+
+	previousPath := ""
+	if _, err = os.Stat(dbPath); err == nil {
+		previousPath = fmt.Sprintf("%s.pre-restore-%s.bak", dbPath, time.Now().Format("20060102-150405"))
+		if err = os.Rename(dbPath, previousPath); err != nil {
+			_ = os.Remove(tmpPath)
+			return "", err
+		}
+	}
+
+	return previousPath, os.Rename(tmpPath, dbPath)
+}
+
+// readSnapshotVersion opens a database file to make sure it is readable, and returns its version
+func readSnapshotVersion(path string) (int, error) {
+	snapshot, err := buntdb.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer snapshot.Close()
+
+	return fetchDatabaseVersion(snapshot)
+}
+
+// copyFile copies src file content to dst, dst is created or truncated
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+
+	if _, err = io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+
+	if err = out.Sync(); err != nil {
+		_ = out.Close()
+		return err
+	}
+
+	return out.Close()
+}
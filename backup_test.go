@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tidwall/buntdb"
+)
+
+// writeSnapshot saves a database snapshot containing fixture keys
+func writeSnapshot(t *testing.T, path string, fixture map[string]string) {
+	t.Helper()
+	if err := snapshotDatabase(openFixtureDatabase(t, fixture), path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRestoreDatabase(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "data.db")
+	writeSnapshot(t, dbPath, map[string]string{"version": "5", "guild-settings:1": "{}"})
+
+	snapshotPath := filepath.Join(dir, "snapshot.db")
+	writeSnapshot(t, snapshotPath, map[string]string{"version": "5", "guild-settings:2": "{}"})
+	snapshot, err := os.ReadFile(snapshotPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	previousPath, err := restoreDatabase(snapshotPath, dbPath)
+	if err != nil {
+		t.Fatalf("restoreDatabase failed: %v", err)
+	}
+	if _, err = os.Stat(previousPath); err != nil {
+		t.Errorf("previous data file not kept aside: %v", err)
+	}
+
+	if after, _ := os.ReadFile(snapshotPath); !bytes.Equal(after, snapshot) {
+		t.Error("snapshot file was modified")
+	}
+
+	restored, err := buntdb.Open(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer restored.Close()
+	content := databaseContent(t, restored)
+	if _, ok := content["guild-settings:2"]; !ok || len(content) != 2 {
+		t.Errorf("restored content = %v, want snapshot content", content)
+	}
+}
+
+func TestRestoreDatabaseInvalidSnapshot(t *testing.T) {
+	tests := []struct {
+		name    string
+		content []byte
+	}{
+		{name: "missing"},
+		{name: "empty", content: []byte{}},
+		{name: "not a database", content: []byte("not a database\n")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			dbPath := filepath.Join(dir, "data.db")
+			writeSnapshot(t, dbPath, map[string]string{"version": "5"})
+			data, err := os.ReadFile(dbPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			snapshotPath := filepath.Join(dir, "snapshot.db")
+			if tt.content != nil {
+				if err = os.WriteFile(snapshotPath, tt.content, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if _, err = restoreDatabase(snapshotPath, dbPath); err == nil {
+				t.Fatal("restoreDatabase succeeded, want invalid snapshot error")
+			}
+
+			if after, _ := os.ReadFile(dbPath); !bytes.Equal(after, data) {
+				t.Error("data file was replaced")
+			}
+			if tt.content == nil {
+				if _, err = os.Stat(snapshotPath); !os.IsNotExist(err) {
+					t.Error("missing snapshot file was created")
+				}
+			}
+			if _, err = os.Stat(dbPath + ".restore"); !os.IsNotExist(err) {
+				t.Error("temporary copy was not removed")
+			}
+		})
+	}
+}
+
+func TestSnapshotDatabase(t *testing.T) {
+	dir := t.TempDir()
+	fixtureDB := openFixtureDatabase(t, map[string]string{"version": "5"})
+
+	path := filepath.Join(dir, "snapshot.db")
+	if err := snapshotDatabase(fixtureDB, path); err != nil {
+		t.Fatalf("snapshotDatabase failed: %v", err)
+	}
+	if version, err := readSnapshotVersion(path); err != nil || version != 5 {
+		t.Errorf("snapshot version = %d, %v, want 5", version, err)
+	}
+
+	// A directory cannot be replaced, the snapshot fails once written
+	failingPath := filepath.Join(dir, "directory.db")
+	if err := os.Mkdir(failingPath, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := snapshotDatabase(fixtureDB, failingPath); err == nil {
+		t.Error("snapshotDatabase succeeded over a directory")
+	}
+
+	for _, tmpPath := range []string{path + ".tmp", failingPath + ".tmp"} {
+		if _, err := os.Stat(tmpPath); !os.IsNotExist(err) {
+			t.Errorf("temporary file %s was not removed", tmpPath)
+		}
+	}
+}
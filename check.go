@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/rs/zerolog/log"
+	"github.com/tidwall/buntdb"
+	"github.com/zergrael/epa/wclogs"
+)
+
+// runCheckCommand queries WarcraftLogs for a character like a guild tick, printing announcements instead of sending them
+// Credentials are read from WCL_CLIENT_ID and WCL_CLIENT_SECRET env variables unless replaying fixtures, Discord is never reached
+func runCheckCommand(args []string) {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	name := fs.String("character", "", "Character name")
+	server := fs.String("server", "", "Character server")
+	region := fs.String("region", "EU", "Character server region (EU/US)")
+	flavorName := fs.String("flavor", wclogs.Classic.String(), "WarcraftLogs flavor, retail, classic or vanilla")
+	snapshotPath := fs.String("parses-db", "", "Database snapshot to compare parses against, a copy is read")
+	recordDir := fs.String("record", "", "Save WarcraftLogs API responses as fixtures into this directory")
+	replayDir := fs.String("replay", "", "Answer WarcraftLogs API requests from fixtures of this directory, without credentials")
+	_ = fs.Parse(args)
+
+	if *name == "" || *server == "" {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	flavor, err := wclogs.ParseFlavor(*flavorName)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Invalid flavor")
+	}
+
+	var httpClient *http.Client
+	if *replayDir != "" {
+		httpClient = &http.Client{Transport: wclogs.NewReplayer(*replayDir)}
+	} else {
+		creds := &wclogs.Credentials{ClientID: os.Getenv("WCL_CLIENT_ID"), ClientSecret: os.Getenv("WCL_CLIENT_SECRET")}
+		if creds.ClientID == "" || creds.ClientSecret == "" {
+			log.Fatal().Msg("Missing WCL_CLIENT_ID / WCL_CLIENT_SECRET env variables")
+		}
+
+		httpClient = creds.HTTPClient()
+		if *recordDir != "" {
+			httpClient.Transport = wclogs.NewRecorder(*recordDir, httpClient.Transport)
+		}
+	}
+
+	ctx := context.Background()
+	w := wclogs.NewWithHTTPClient(flavor, httpClient, nil)
+	if err := w.Connect(ctx); err != nil {
+		log.Fatal().Err(err).Msg("These API credentials cannot be used")
+	}
+
+	char, err := w.GetCharacter(ctx, *name, *server, *region)
+	if err != nil || char == nil {
+		log.Fatal().Err(err).Str("name", *name).Msg("GetCharacter failed")
+	}
+	log.Info().Str("slug", char.Slug()).Int("charID", char.ID).Str("class", char.ClassName()).Msg("Character found")
+
+	metadata, err := w.GetLatestReportMetadata(ctx, char)
+	if err != nil {
+		log.Fatal().Err(err).Str("slug", char.Slug()).Msg("GetLatestReportMetadata failed")
+	}
+
+	report, err := w.GetReport(ctx, metadata.Code)
+	if err != nil {
+		log.Fatal().Err(err).Str("code", metadata.Code).Msg("GetReport failed")
+	}
+	log.Info().Str("code", report.Code).Interface("raids", report.Raids).
+		Int("fights", len(report.Fights)).Msg("Latest report")
+
+	if roles := report.Roles(char.ID); len(roles) > 0 {
+		char.Roles = roles
+	}
+
+	snapshotParses := checkSnapshotParses(*snapshotPath, char.ID)
+	if snapshotParses != nil {
+		snapshotParses.ResolveUnknownDifficulty(w.Zones())
+	}
+
+	commandNotifiers = []Notifier{consoleNotifier{out: os.Stdout}}
+	trackedChar := &TrackedCharacter{Character: char}
+	notify(&NewReportEvent{
+		ReportCode: report.Code,
+		EndTime:    report.EndTime,
+		Characters: []*TrackedCharacter{trackedChar},
+	})
+
+	var partitionEvents []*NewPartitionEvent
+	for _, raid := range report.CharacterRaids(char.ID) {
+		metricRankings, err := w.GetMetricRankingsForCharacter(ctx, char, raid.ZoneID, raid.Difficulty, raid.Size, char.Metrics())
+		if err != nil {
+			log.Fatal().Err(err).Str("slug", char.Slug()).Msg("GetMetricRankingsForCharacter failed")
+		}
+
+		dbParses := snapshotParses
+		if dbParses == nil {
+			dbParses = checkBaselineParses(report, raid, metricRankings)
+		}
+
+		zone := w.GetZone(raid.ZoneID)
+		if compareParsesAndAnnounce("", metricRankings, dbParses, report, raid, zone, trackedChar) {
+			partitionEvents = addNewPartitionCharacter(partitionEvents, "", raid.ZoneID, zone, metricRankings.Partition(), trackedChar)
+		}
+		dbParses.MergeMetricRankings(raid.ZoneID, raid.Difficulty, raid.Size, metricRankings)
+	}
+
+	for _, event := range partitionEvents {
+		notify(event)
+	}
+}
+
+// checkBaselineParses returns the rankings of a report raid without its killed encounters,
+// so that only encounters killed in the report are announced as first kills
+func checkBaselineParses(report *wclogs.Report, raid wclogs.ReportRaid, metricRankings *wclogs.MetricRankings) *wclogs.Parses {
+	baseline := make(wclogs.MetricRankings)
+	for metric, rankings := range *metricRankings {
+		partitionRankings := wclogs.PartitionRankings{Partition: rankings.Partition}
+		for _, ranking := range rankings.Rankings {
+			if report.LastFightForEncounter(raid, ranking.Encounter.ID) == nil {
+				partitionRankings.Rankings = append(partitionRankings.Rankings, ranking)
+			}
+		}
+		baseline[metric] = partitionRankings
+	}
+
+	parses := make(wclogs.Parses)
+	parses.MergeMetricRankings(raid.ZoneID, raid.Difficulty, raid.Size, &baseline)
+	return &parses
+}
+
+// checkSnapshotParses returns the parses rankings are compared against, read from a copy of a database snapshot
+// Returns nil without snapshot
+func checkSnapshotParses(snapshotPath string, charID int) *wclogs.Parses {
+	if snapshotPath == "" {
+		return nil
+	}
+
+	// Never open the snapshot itself, buntdb rewrites its file
+	tmpDir, err := os.MkdirTemp("", "epa-check")
+	if err != nil {
+		log.Fatal().Err(err).Msg("Cannot create temporary directory")
+	}
+	defer os.RemoveAll(tmpDir)
+
+	copyPath := filepath.Join(tmpDir, "data.db")
+	if err = copyFile(snapshotPath, copyPath); err != nil {
+		log.Fatal().Err(err).Str("path", snapshotPath).Msg("Cannot copy database snapshot")
+	}
+
+	snapshot, err := buntdb.Open(copyPath)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Cannot open database snapshot")
+	}
+	defer snapshot.Close()
+
+	parses, err := fetchWCLogsParsesForCharacterID(snapshot, charID)
+	if err != nil {
+		log.Fatal().Err(err).Int("charID", charID).Msg("No stored parses for character")
+	}
+
+	return parses
+}
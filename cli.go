@@ -0,0 +1,209 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/rs/zerolog/log"
+	"github.com/tidwall/buntdb"
+	"github.com/zergrael/epa/wclogs"
+)
+
+// runCommand runs a one-shot command instead of the bot
+func runCommand(args []string) {
+	switch args[0] {
+	case "db":
+		runDatabaseCommand(args[1:])
+	case "check":
+		runCheckCommand(args[1:])
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+// runDatabaseCommand runs a database maintenance command
+func runDatabaseCommand(args []string) {
+	if len(args) < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	switch args[0] {
+	case "backup":
+		path := newBackupPath()
+		if len(args) > 1 {
+			path = args[1]
+		} else if err := os.MkdirAll(backupDir, 0o755); err != nil {
+			log.Fatal().Err(err).Msg("Cannot create backup directory")
+		}
+
+		db := openDatabase()
+		defer db.Close()
+
+		if err := snapshotDatabase(db, path); err != nil {
+			log.Fatal().Err(err).Msg("Failed to backup database")
+		}
+		log.Info().Str("path", path).Msg("Database backup saved")
+	case "restore":
+		if len(args) < 2 {
+			log.Fatal().Msg("Missing snapshot path")
+		}
+
+		previousPath, err := restoreDatabase(args[1], databasePath)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to restore database")
+		}
+		log.Info().Str("path", databasePath).Str("previous", previousPath).Msg("Database restored")
+	case "guilds":
+		db := openDatabase()
+		defer db.Close()
+
+		guildIDs, err := listGuildIDs(db)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to list guilds")
+		}
+		for _, guildID := range guildIDs {
+			characters, _ := fetchWCLogsTrackedCharacters(db, guildID)
+			_, credsErr := fetchWCLogsCredentials(db, guildID)
+			fmt.Printf("%s\tcharacters=%d\tcredentials=%t\n", guildID, len(characters), credsErr == nil)
+		}
+	case "dump":
+		if len(args) < 2 {
+			log.Fatal().Msg("Missing guild ID")
+		}
+
+		db := openDatabase()
+		defer db.Close()
+		printJSON(dumpGuild(db, args[1]))
+	case "parses":
+		if len(args) < 2 {
+			log.Fatal().Msg("Missing character ID")
+		}
+		charID, err := strconv.Atoi(args[1])
+		if err != nil {
+			log.Fatal().Err(err).Msg("Invalid character ID")
+		}
+
+		db := openDatabase()
+		defer db.Close()
+
+		dump := struct {
+			LatestReport *wclogs.ReportMetadata
+			Parses       *wclogs.Parses
+		}{}
+		dump.LatestReport, _ = fetchWCLogsLatestReportForCharacterID(db, charID)
+		dump.Parses, _ = fetchWCLogsParsesForCharacterID(db, charID)
+		if dump.LatestReport == nil && dump.Parses == nil {
+			log.Fatal().Int("charID", charID).Msg("No stored data for character")
+		}
+		printJSON(dump)
+	case "migrate":
+		db := openDatabase()
+		defer db.Close()
+
+		if err := upgradeDatabaseIfNecessary(db, databasePath); err != nil {
+			log.Fatal().Err(err).Msg("Failed to apply database migrations")
+		}
+		log.Info().Int("version", currentDatabaseVersion).Msg("Database is up to date")
+	case "check":
+		db := openDatabase()
+		defer db.Close()
+
+		issues, err := findDatabaseInconsistencies(db)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to check database")
+		}
+		for _, issue := range issues {
+			fmt.Println(issue)
+		}
+
+		if len(args) > 1 && args[1] == "fix" {
+			orphans, err := collectOrphanedWCLogsCharacterData(db)
+			if err != nil {
+				log.Fatal().Err(err).Msg("Failed to collect orphaned character data")
+			}
+			log.Info().Ints("charIDs", orphans).Msg("Collected orphaned character data")
+		} else if len(issues) > 0 {
+			os.Exit(1)
+		}
+	case "delete-guild":
+		if len(args) < 2 {
+			log.Fatal().Msg("Missing guild ID")
+		}
+
+		db := openDatabase()
+		defer db.Close()
+
+		charIDs, err := deleteGuildData(db, args[1])
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to delete guild data")
+		}
+		log.Info().Str("guildID", args[1]).Ints("charIDs", charIDs).Msg("Deleted guild data")
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+// openDatabase opens the database file for a one-shot command, the bot must not be running on it
+func openDatabase() *buntdb.DB {
+	if _, err := os.Stat(databasePath); err != nil {
+		log.Fatal().Err(err).Msg("Cannot find database")
+	}
+
+	db, err := buntdb.Open(databasePath)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Cannot open database")
+	}
+
+	return db
+}
+
+// guildDump represents every stored data of a guild, secrets excluded
+type guildDump struct {
+	GuildID           string
+	Settings          *GuildSettings
+	ClientID          string `json:",omitempty"`
+	TrackedCharacters []*TrackedCharacter
+	WebhookChannelIDs []string
+}
+
+// dumpGuild reads every stored data of a guild
+func dumpGuild(db *buntdb.DB, guildID string) *guildDump {
+	dump := &guildDump{GuildID: guildID}
+
+	var err error
+	if dump.Settings, err = fetchGuildSettings(db, guildID); err != nil {
+		log.Fatal().Err(err).Msg("Failed to read guild settings")
+	}
+	if creds, err := fetchWCLogsCredentials(db, guildID); err == nil {
+		dump.ClientID = creds.ClientID
+	}
+	if dump.TrackedCharacters, err = fetchWCLogsTrackedCharacters(db, guildID); err != nil && err != buntdb.ErrNotFound {
+		log.Fatal().Err(err).Msg("Failed to read tracked characters")
+	}
+
+	webhooks, err := fetchChannelWebhooks(db, guildID)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to read channel webhooks")
+	}
+	for channelID := range webhooks {
+		dump.WebhookChannelIDs = append(dump.WebhookChannelIDs, channelID)
+	}
+
+	return dump
+}
+
+// printJSON prints v as indented JSON on stdout
+func printJSON(v interface{}) {
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to encode JSON")
+	}
+
+	fmt.Println(string(out))
+}
@@ -1,24 +1,27 @@
 package main
 
 import (
-	"fmt"
-	"strconv"
-
 	"github.com/bwmarrin/discordgo"
 	"github.com/rs/zerolog/log"
 )
 
-var falsePointer = false
+// manageServerPermission restricts administration commands to members allowed to manage the guild
+var manageServerPermission int64 = discordgo.PermissionManageServer
+
+// dmPermission hides commands from direct messages, every command works on a guild
+var dmPermission = false
 
 var commands = []*discordgo.ApplicationCommand{
 	{
-		Name:        "epa",
-		Description: "Display configuration & information about the bot",
+		Name:         "epa",
+		Description:  "Display configuration & information about the bot",
+		DMPermission: &dmPermission,
 	},
 	{
-		Name:              "register-warcraftlogs",
-		Description:       "Setup credentials for WarcraftLogs API",
-		DefaultPermission: &falsePointer,
+		Name:                     "register-warcraftlogs",
+		Description:              "Setup credentials for WarcraftLogs API",
+		DMPermission:             &dmPermission,
+		DefaultMemberPermissions: &manageServerPermission,
 		Options: []*discordgo.ApplicationCommandOption{
 			{
 				Type:        discordgo.ApplicationCommandOptionString,
@@ -35,13 +38,100 @@ var commands = []*discordgo.ApplicationCommand{
 		},
 	},
 	{
-		Name:              "unregister-warcraftlogs",
-		Description:       "Erase WarcraftLogs API credentials",
-		DefaultPermission: &falsePointer,
+		Name:                     "unregister-warcraftlogs",
+		Description:              "Erase WarcraftLogs API credentials",
+		DMPermission:             &dmPermission,
+		DefaultMemberPermissions: &manageServerPermission,
+	},
+	{
+		Name:                     "set-manager-role",
+		Description:              "Restrict tracked characters changes to a role, everyone is allowed without one",
+		DMPermission:             &dmPermission,
+		DefaultMemberPermissions: &manageServerPermission,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionRole,
+				Name:        "role",
+				Description: "EPA manager role, leave empty to allow everyone",
+				Required:    false,
+			},
+		},
+	},
+	{
+		Name:                     "set-language",
+		Description:              "Set the default language of responses and announcements",
+		DMPermission:             &dmPermission,
+		DefaultMemberPermissions: &manageServerPermission,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "language",
+				Description: "Default language",
+				Required:    true,
+				Choices: []*discordgo.ApplicationCommandOptionChoice{
+					{Name: langNames[LangEnglish], Value: string(LangEnglish)},
+					{Name: langNames[LangFrench], Value: string(LangFrench)},
+				},
+			},
+		},
+	},
+	{
+		Name:                     "set-webhook-announcements",
+		Description:              "Send announcements under character names through a channel webhook",
+		DMPermission:             &dmPermission,
+		DefaultMemberPermissions: &manageServerPermission,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionBoolean,
+				Name:        "enabled",
+				Description: "Announce under character names",
+				Required:    true,
+			},
+		},
+	},
+	{
+		Name:                     "notification-webhooks",
+		Description:              "Manage HTTP webhooks receiving tracking events as JSON",
+		DMPermission:             &dmPermission,
+		DefaultMemberPermissions: &manageServerPermission,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "add",
+				Description: "Send tracking events to a webhook",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "url",
+						Description: "Webhook URL",
+						Required:    true,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "remove",
+				Description: "Stop sending tracking events to a webhook",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "url",
+						Description: "Webhook URL",
+						Required:    true,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "list",
+				Description: "List webhooks receiving tracking events",
+			},
+		},
 	},
 	{
-		Name:        "track-character",
-		Description: "Add WCLogs parses tracking on a specific character",
+		Name:         "track-character",
+		Description:  "Add WCLogs parses tracking on a specific character",
+		DMPermission: &dmPermission,
 		Options: []*discordgo.ApplicationCommandOption{
 			{
 				Type:        discordgo.ApplicationCommandOptionString,
@@ -70,8 +160,9 @@ var commands = []*discordgo.ApplicationCommand{
 		},
 	},
 	{
-		Name:        "untrack-character",
-		Description: "Remove WCLogs parses tracking on a specific character",
+		Name:         "untrack-character",
+		Description:  "Remove WCLogs parses tracking on a specific character",
+		DMPermission: &dmPermission,
 		Options: []*discordgo.ApplicationCommandOption{
 			{
 				Type:        discordgo.ApplicationCommandOptionString,
@@ -94,8 +185,9 @@ var commands = []*discordgo.ApplicationCommand{
 		},
 	},
 	{
-		Name:        "parses",
-		Description: "Show current parses for a specific character",
+		Name:         "parses",
+		Description:  "Show current parses for a specific character",
+		DMPermission: &dmPermission,
 		Options: []*discordgo.ApplicationCommandOption{
 			{
 				Type:        discordgo.ApplicationCommandOptionString,
@@ -118,20 +210,22 @@ var commands = []*discordgo.ApplicationCommand{
 		},
 	},
 	{
-		Name:        "list-tracked-characters",
-		Description: "List WCLogs parses tracked characters",
+		Name:         "list-tracked-characters",
+		Description:  "List WCLogs parses tracked characters",
+		DMPermission: &dmPermission,
 	},
 }
 
 var commandsHandlers = map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){
 	"epa": func(s *discordgo.Session, i *discordgo.InteractionCreate) {
-		response := "Hello there\n"
-		if logs[i.GuildID] != nil {
-			response += "WarcraftLogs engine is running, currently tracking " +
-				strconv.Itoa(len(trackedCharacters[i.GuildID])) +
-				" characters, see /track-character command to add more."
+		lang := interactionLang(i)
+		response := tr(lang, "epa.hello") + "\n"
+		if guildWCLogs(i.GuildID) != nil {
+			response += tr(lang, "epa.running", len(guildTrackedCharacters(i.GuildID)))
+		} else if credentialsRejected(i.GuildID) {
+			response += tr(lang, "wclogs.unauthorized")
 		} else {
-			response += "WarcraftLogs is disabled, see /register-warcraftlogs command as an admin"
+			response += tr(lang, "epa.disabled")
 		}
 
 		err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
@@ -150,7 +244,15 @@ var commandsHandlers = map[string]func(s *discordgo.Session, i *discordgo.Intera
 		clientID := i.ApplicationCommandData().Options[0].StringValue()
 		clientSecret := i.ApplicationCommandData().Options[1].StringValue()
 
-		response := registerWarcraftLogs(clientID, clientSecret, i.GuildID)
+		// Credentials are checked against WarcraftLogs API, answer later
+		if !deferResponse(s, i, true) {
+			return
+		}
+
+		editResponseContent(s, i, registerWarcraftLogs(clientID, clientSecret, i.GuildID, interactionLang(i)))
+	},
+	"unregister-warcraftlogs": func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+		response := unregisterWarcraftLogs(i.GuildID, interactionLang(i))
 
 		err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 			Type: discordgo.InteractionResponseChannelMessageWithSource,
@@ -161,11 +263,16 @@ var commandsHandlers = map[string]func(s *discordgo.Session, i *discordgo.Intera
 		})
 
 		if err != nil {
-			log.Error().Err(err).Msg("/register-warcraftlogs command response failed")
+			log.Error().Err(err).Msg("/unregister-warcraftlogs command response failed")
 		}
 	},
-	"unregister-warcraftlogs": func(s *discordgo.Session, i *discordgo.InteractionCreate) {
-		response := unregisterWarcraftLogs(i.GuildID)
+	"set-manager-role": func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+		roleID := ""
+		if len(i.ApplicationCommandData().Options) > 0 {
+			roleID = i.ApplicationCommandData().Options[0].RoleValue(s, i.GuildID).ID
+		}
+
+		response := setManagerRole(i.GuildID, roleID, interactionLang(i))
 
 		err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 			Type: discordgo.InteractionResponseChannelMessageWithSource,
@@ -176,74 +283,137 @@ var commandsHandlers = map[string]func(s *discordgo.Session, i *discordgo.Intera
 		})
 
 		if err != nil {
-			log.Error().Err(err).Msg("/unregister-warcraftlogs command response failed")
+			log.Error().Err(err).Msg("/set-manager-role command response failed")
 		}
 	},
-	"track-character": func(s *discordgo.Session, i *discordgo.InteractionCreate) {
-		char := i.ApplicationCommandData().Options[0].StringValue()
-		server := i.ApplicationCommandData().Options[1].StringValue()
-		region := i.ApplicationCommandData().Options[2].StringValue()
-		channel := i.ChannelID
-		if len(i.ApplicationCommandData().Options) > 3 {
-			channel = i.ApplicationCommandData().Options[3].ChannelValue(s).ID
+	"set-language": func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+		lang := Lang(i.ApplicationCommandData().Options[0].StringValue())
+
+		response := setLanguage(i.GuildID, lang)
+
+		err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: response,
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		})
+
+		if err != nil {
+			log.Error().Err(err).Msg("/set-language command response failed")
 		}
+	},
+	"set-webhook-announcements": func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+		enabled := i.ApplicationCommandData().Options[0].BoolValue()
 
-		response := trackCharacter(char, server, region, i.GuildID, channel)
+		response := setWebhookAnnouncements(i.GuildID, enabled, interactionLang(i))
 
 		err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 			Type: discordgo.InteractionResponseChannelMessageWithSource,
 			Data: &discordgo.InteractionResponseData{
 				Content: response,
+				Flags:   discordgo.MessageFlagsEphemeral,
 			},
 		})
 
 		if err != nil {
-			log.Error().Err(err).Msg("/track-character command response failed")
+			log.Error().Err(err).Msg("/set-webhook-announcements command response failed")
 		}
 	},
-	"untrack-character": func(s *discordgo.Session, i *discordgo.InteractionCreate) {
-		char := i.ApplicationCommandData().Options[0].StringValue()
-		server := i.ApplicationCommandData().Options[1].StringValue()
-		region := i.ApplicationCommandData().Options[2].StringValue()
+	"notification-webhooks": func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+		lang := interactionLang(i)
+		subCommand := i.ApplicationCommandData().Options[0]
 
-		response := untrackCharacter(char, server, region, i.GuildID)
+		var response string
+		switch subCommand.Name {
+		case "add":
+			response = addNotificationWebhook(i.GuildID, subCommand.Options[0].StringValue(), lang)
+		case "remove":
+			response = removeNotificationWebhook(i.GuildID, subCommand.Options[0].StringValue(), lang)
+		default:
+			response = listNotificationWebhooks(i.GuildID, lang)
+		}
 
 		err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 			Type: discordgo.InteractionResponseChannelMessageWithSource,
 			Data: &discordgo.InteractionResponseData{
 				Content: response,
+				Flags:   discordgo.MessageFlagsEphemeral,
 			},
 		})
 
 		if err != nil {
-			log.Error().Err(err).Msg("/untrack-character command response failed")
+			log.Error().Err(err).Msg("/notification-webhooks command response failed")
+		}
+	},
+	"track-character": func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+		if !respondIfNotManager(s, i) {
+			return
+		}
+
+		char := i.ApplicationCommandData().Options[0].StringValue()
+		server := i.ApplicationCommandData().Options[1].StringValue()
+		region := i.ApplicationCommandData().Options[2].StringValue()
+		channel := i.ChannelID
+		if len(i.ApplicationCommandData().Options) > 3 {
+			channel = i.ApplicationCommandData().Options[3].ChannelValue(s).ID
+		}
+
+		// Character lookup queries WarcraftLogs API, answer later
+		if !deferResponse(s, i, false) {
+			return
+		}
+
+		editResponseContent(s, i, trackCharacter(char, server, region, i.GuildID, channel, interactionLang(i)))
+	},
+	"untrack-character": func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+		if !respondIfNotManager(s, i) {
+			return
+		}
+
+		char := i.ApplicationCommandData().Options[0].StringValue()
+		server := i.ApplicationCommandData().Options[1].StringValue()
+		region := i.ApplicationCommandData().Options[2].StringValue()
+
+		// Character lookup queries WarcraftLogs API, answer later
+		if !deferResponse(s, i, false) {
+			return
 		}
+
+		editResponseContent(s, i, untrackCharacter(char, server, region, i.GuildID, interactionLang(i)))
+	},
+	"parses": func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+		char := i.ApplicationCommandData().Options[0].StringValue()
+		server := i.ApplicationCommandData().Options[1].StringValue()
+		region := i.ApplicationCommandData().Options[2].StringValue()
+
+		// Fetching all zones parses is slow, answer later
+		if !deferResponse(s, i, false) {
+			return
+		}
+
+		lang := interactionLang(i)
+		edit := &discordgo.WebhookEdit{}
+		character, parses, errorStr := getParsesForCharacter(char, server, region, i.GuildID, lang)
+		if errorStr != "" {
+			edit.Content = &errorStr
+		} else {
+			embeds := parsesEmbeds(guildWCLogs(i.GuildID), character, parses, lang)
+			edit.Embeds = &embeds
+		}
+
+		editResponse(s, i, edit)
 	},
 	"list-tracked-characters": func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+		lang := interactionLang(i)
 		var data *discordgo.InteractionResponseData
-		chars, errorStr := getTrackedCharacters(i.GuildID)
+		chars, errorStr := getTrackedCharacters(i.GuildID, lang)
 		if errorStr != "" {
 			data = &discordgo.InteractionResponseData{
 				Content: errorStr,
 			}
 		} else {
-			var charsStr = ""
-			for _, char := range chars {
-				charsStr += fmt.Sprintf("%s\n", char.Slug())
-			}
-			// TODO: Add the latest report EndTime from db
-			data = &discordgo.InteractionResponseData{
-				Embeds: []*discordgo.MessageEmbed{
-					{
-						Type: discordgo.EmbedTypeRich,
-						Fields: []*discordgo.MessageEmbedField{{
-							Name:   "Tracked characters",
-							Value:  charsStr,
-							Inline: true,
-						}},
-					},
-				},
-			}
+			data = trackedCharactersPage(chars, 0, lang)
 		}
 
 		err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
@@ -257,6 +427,74 @@ var commandsHandlers = map[string]func(s *discordgo.Session, i *discordgo.Intera
 	},
 }
 
+// deferResponse acknowledges an interaction, the response has to be sent later with editResponse
+// Returns false if the interaction could not be acknowledged
+func deferResponse(s *discordgo.Session, i *discordgo.InteractionCreate, ephemeral bool) bool {
+	data := &discordgo.InteractionResponseData{}
+	if ephemeral {
+		data.Flags = discordgo.MessageFlagsEphemeral
+	}
+
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: data,
+	})
+
+	if err != nil {
+		log.Error().Err(err).Msg("Deferred response failed")
+		return false
+	}
+
+	return true
+}
+
+// editResponse replaces a deferred interaction response
+func editResponse(s *discordgo.Session, i *discordgo.InteractionCreate, edit *discordgo.WebhookEdit) {
+	_, err := s.InteractionResponseEdit(i.Interaction, edit)
+	if err != nil {
+		log.Error().Err(err).Msg("Deferred response edit failed")
+	}
+}
+
+// editResponseContent replaces a deferred interaction response with a text message
+func editResponseContent(s *discordgo.Session, i *discordgo.InteractionCreate, content string) {
+	editResponse(s, i, &discordgo.WebhookEdit{Content: &content})
+}
+
+// respondIfNotManager responds with a denial message if the interaction member is not an EPA manager
+// Returns true if the member is allowed to change tracked characters
+func respondIfNotManager(s *discordgo.Session, i *discordgo.InteractionCreate) bool {
+	allowed, roleID := isManager(i.GuildID, i.Member)
+	if allowed {
+		return true
+	}
+
+	// Member is only set within a guild, User otherwise
+	user := i.User
+	if i.Member != nil {
+		user = i.Member.User
+	}
+	userID := ""
+	if user != nil {
+		userID = user.ID
+	}
+
+	log.Info().Str("guildID", i.GuildID).Str("userID", userID).Msg("Denied roster change")
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: tr(interactionLang(i), "manager.denied", roleID),
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	})
+
+	if err != nil {
+		log.Error().Err(err).Msg("Denial response failed")
+	}
+
+	return false
+}
+
 func addCommands(guildID string) {
 	log.Debug().Str("guildID", guildID).Msg("Adding commands...")
 
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/rs/zerolog/log"
+)
+
+// componentsHandlers routes message component interactions by custom ID prefix, arguments follow colons
+var componentsHandlers = map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate, args []string){
+	"show-parses": func(s *discordgo.Session, i *discordgo.InteractionCreate, args []string) {
+		lang := interactionLang(i)
+		data := &discordgo.InteractionResponseData{Flags: discordgo.MessageFlagsEphemeral}
+		char := componentTrackedCharacter(i.GuildID, args)
+		if char == nil {
+			data.Content = tr(lang, "untrack.gone")
+		} else if parses, err := fetchWCLogsParsesForCharacterID(db, char.ID); err != nil {
+			data.Content = tr(lang, "parses.none-stored", char.Slug())
+		} else {
+			data.Embeds = parsesEmbeds(guildWCLogs(i.GuildID), char.Character, parses, lang)
+		}
+
+		err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: data,
+		})
+
+		if err != nil {
+			log.Error().Err(err).Msg("show-parses component response failed")
+		}
+	},
+	"untrack": func(s *discordgo.Session, i *discordgo.InteractionCreate, args []string) {
+		if !respondIfNotManager(s, i) {
+			return
+		}
+
+		lang := interactionLang(i)
+		response := tr(lang, "untrack.gone")
+		if char := componentTrackedCharacter(i.GuildID, args); char != nil {
+			response = removeTrackedCharacter(i.GuildID, char.Character, lang)
+		}
+
+		err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: response,
+			},
+		})
+
+		if err != nil {
+			log.Error().Err(err).Msg("untrack component response failed")
+		}
+	},
+	"tracked-page": func(s *discordgo.Session, i *discordgo.InteractionCreate, args []string) {
+		page := 0
+		if len(args) > 0 {
+			page, _ = strconv.Atoi(args[0])
+		}
+
+		lang := interactionLang(i)
+		var data *discordgo.InteractionResponseData
+		chars, errorStr := getTrackedCharacters(i.GuildID, lang)
+		if errorStr != "" {
+			data = &discordgo.InteractionResponseData{Content: errorStr}
+		} else {
+			data = trackedCharactersPage(chars, page, lang)
+		}
+
+		err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseUpdateMessage,
+			Data: data,
+		})
+
+		if err != nil {
+			log.Error().Err(err).Msg("tracked-page component response failed")
+		}
+	},
+}
+
+// componentsHandler dispatches a message component interaction to its handler
+func componentsHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	parts := strings.Split(i.MessageComponentData().CustomID, ":")
+	if componentFunc, ok := componentsHandlers[parts[0]]; ok {
+		componentFunc(s, i, parts[1:])
+		return
+	}
+
+	log.Warn().Str("customID", i.MessageComponentData().CustomID).Msg("Unknown component")
+}
+
+// characterComponentID returns a component custom ID targeting a character ID
+func characterComponentID(action string, charID int) string {
+	return action + ":" + strconv.Itoa(charID)
+}
+
+// trackedCharactersPageComponentID returns a component custom ID showing a tracked characters page
+func trackedCharactersPageComponentID(page int) string {
+	return "tracked-page:" + strconv.Itoa(page)
+}
+
+// componentTrackedCharacter returns the guild tracked character targeted by component arguments, or nil
+func componentTrackedCharacter(guildID string, args []string) *TrackedCharacter {
+	if len(args) < 1 {
+		return nil
+	}
+
+	charID, err := strconv.Atoi(args[0])
+	if err != nil {
+		return nil
+	}
+
+	return findTrackedCharacter(guildID, charID)
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultConfigPath is the configuration file read when none is provided, it may not exist
+const defaultConfigPath = "storage/config.yml"
+
+// Config contains every setting readable from the configuration file
+// Environment variables and flags override its values
+type Config struct {
+	Token          string `yaml:"token"`
+	Debug          bool   `yaml:"debug"`
+	LogFormat      string `yaml:"log_format"`
+	HTTPAddr       string `yaml:"http_addr"`
+	GlobalCommands bool   `yaml:"global_commands"`
+	// ShutdownTimeout bounds the wait for in-flight work on shutdown
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	Database        struct {
+		Path            string        `yaml:"path"`
+		BackupDir       string        `yaml:"backup_dir"`
+		BackupInterval  time.Duration `yaml:"backup_interval"`
+		BackupRetention int           `yaml:"backup_retention"`
+		ShrinkInterval  time.Duration `yaml:"shrink_interval"`
+	} `yaml:"database"`
+	Polling struct {
+		MinInterval time.Duration `yaml:"min_interval"`
+		MaxInterval time.Duration `yaml:"max_interval"`
+	} `yaml:"polling"`
+	Announcements struct {
+		ImprovementThreshold float64 `yaml:"improvement_threshold"`
+		BadParseThreshold    float64 `yaml:"bad_parse_threshold"`
+		ReportsURL           string  `yaml:"reports_url"`
+	} `yaml:"announcements"`
+}
+
+// defaultConfig returns the Config used when no configuration file exists
+func defaultConfig() *Config {
+	var cfg Config
+	cfg.LogFormat = "console"
+	cfg.HTTPAddr = ":8080"
+	cfg.ShutdownTimeout = 30 * time.Second
+	cfg.Database.Path = "storage/data.db"
+	cfg.Database.BackupDir = "storage/backups"
+	cfg.Database.BackupInterval = 24 * time.Hour
+	cfg.Database.BackupRetention = 7
+	cfg.Database.ShrinkInterval = 24 * time.Hour
+	cfg.Polling.MinInterval = 1 * time.Minute
+	cfg.Polling.MaxInterval = 15 * time.Minute
+	cfg.Announcements.ImprovementThreshold = 0.1
+	cfg.Announcements.BadParseThreshold = 50
+	cfg.Announcements.ReportsURL = "https://classic.warcraftlogs.com/reports/"
+
+	return &cfg
+}
+
+// loadConfig reads the configuration file over default values, a missing default configuration file is not an error
+func loadConfig(path string) (*Config, error) {
+	cfg := defaultConfig()
+
+	bytes, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && path == defaultConfigPath {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err = yaml.Unmarshal(bytes, cfg); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// lookupConfigPath returns the configuration file path from -config flag, CONFIG_FILE env variable or default path
+// Flags are not parsed yet as their default values depend on the configuration file
+func lookupConfigPath(args []string) string {
+	for idx, arg := range args {
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") || name != "config" {
+			continue
+		}
+		if hasValue {
+			return value
+		}
+		if idx+1 < len(args) {
+			return args[idx+1]
+		}
+	}
+
+	return lookupEnvOrString("CONFIG_FILE", defaultConfigPath)
+}
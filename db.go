@@ -2,92 +2,104 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
+	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/tidwall/buntdb"
 	"github.com/zergrael/epa/wclogs"
 )
 
-const currentDatabaseVersion = 4
-
-// upgradeDatabaseIfNecessary checks database version and tries to migrate if necessary
-func upgradeDatabaseIfNecessary(db *buntdb.DB) error {
-	dbVersion := 0
-
+// fetchGuildSettings reads a guild settings, missing settings are returned empty
+func fetchGuildSettings(db *buntdb.DB, guildID string) (*GuildSettings, error) {
+	var settings GuildSettings
 	err := db.View(func(tx *buntdb.Tx) error {
-		val, err := tx.Get("version")
-		// Ignore not-found errors
-		if err == nil {
-			dbVersion, _ = strconv.Atoi(val)
+		val, err := tx.Get("guild-settings:" + guildID)
+		if err == buntdb.ErrNotFound {
+			return nil
 		}
-		return nil
+		if err != nil {
+			return err
+		}
+
+		return json.Unmarshal([]byte(val), &settings)
 	})
+
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	if dbVersion >= currentDatabaseVersion {
-		return nil
+	return &settings, nil
+}
+
+func storeGuildSettings(db *buntdb.DB, guildID string, settings *GuildSettings) error {
+	bytes, err := json.Marshal(settings)
+	if err != nil {
+		return err
 	}
 
-	// Migration time !
-	switch dbVersion {
-	case 0:
-		fallthrough
-	case 1:
-		// Basically delete everything
-		db.Update(func(tx *buntdb.Tx) error {
-			return tx.DeleteAll()
-		})
-		fallthrough
-	case 2:
-		var keysToDelete []string
-		db.Update(func(tx *buntdb.Tx) error {
-			err := tx.AscendKeys("wclogs-parses:*", func(key, value string) bool {
-				keysToDelete = append(keysToDelete, key)
-				return true
-			})
-			if err != nil {
-				return err
-			}
+	err = db.Update(func(tx *buntdb.Tx) error {
+		_, _, err := tx.Set("guild-settings:"+guildID, string(bytes), nil)
+		return err
+	})
 
-			for _, key := range keysToDelete {
-				if _, err = tx.Delete(key); err != nil {
-					return err
-				}
-			}
+	return err
+}
+
+// ChannelWebhook identifies the webhook created by the bot in a channel
+type ChannelWebhook struct {
+	ID    string
+	Token string
+}
 
+// fetchChannelWebhook reads the bot webhook of a guild channel, nil if none was created yet
+func fetchChannelWebhook(db *buntdb.DB, guildID, channelID string) (*ChannelWebhook, error) {
+	var webhook *ChannelWebhook
+	err := db.View(func(tx *buntdb.Tx) error {
+		val, err := tx.Get("discord-webhook:" + guildID + ":" + channelID)
+		if err == buntdb.ErrNotFound {
+			return nil
+		}
+		if err != nil {
 			return err
-		})
-	case 3:
-		var keysToDelete []string
-		db.Update(func(tx *buntdb.Tx) error {
-			err := tx.AscendKeys("wclogs-latest-report:*", func(key, value string) bool {
-				keysToDelete = append(keysToDelete, key)
-				return true
-			})
-			if err != nil {
-				return err
-			}
+		}
 
-			for _, key := range keysToDelete {
-				if _, err = tx.Delete(key); err != nil {
-					return err
-				}
-			}
+		webhook = &ChannelWebhook{}
+		return json.Unmarshal([]byte(val), webhook)
+	})
 
-			return err
-		})
-	case 4:
-		// Current version
+	if err != nil {
+		return nil, err
 	}
 
-	db.Update(func(tx *buntdb.Tx) error {
-		_, _, err := tx.Set("version", strconv.Itoa(currentDatabaseVersion), nil)
+	return webhook, nil
+}
+
+func storeChannelWebhook(db *buntdb.DB, guildID, channelID string, webhook *ChannelWebhook) error {
+	bytes, err := json.Marshal(webhook)
+	if err != nil {
+		return err
+	}
+
+	err = db.Update(func(tx *buntdb.Tx) error {
+		_, _, err := tx.Set("discord-webhook:"+guildID+":"+channelID, string(bytes), nil)
 		return err
 	})
 
-	return nil
+	return err
+}
+
+func deleteChannelWebhook(db *buntdb.DB, guildID, channelID string) error {
+	err := db.Update(func(tx *buntdb.Tx) error {
+		_, err := tx.Delete("discord-webhook:" + guildID + ":" + channelID)
+		if err == buntdb.ErrNotFound {
+			return nil
+		}
+		return err
+	})
+
+	return err
 }
 
 func fetchWCLogsCredentials(db *buntdb.DB, guildID string) (*wclogs.Credentials, error) {
@@ -186,6 +198,311 @@ func storeWCLogsTrackedCharacters(db *buntdb.DB, guildID string, characters []*T
 	return err
 }
 
+// updateWCLogsTrackedCharacters stores a guild roster and, in the same transaction, deletes per-character data
+// of removedCharIDs when no guild tracks them anymore
+func updateWCLogsTrackedCharacters(db *buntdb.DB, guildID string, characters []*TrackedCharacter, removedCharIDs []int) error {
+	bytes, err := json.Marshal(characters)
+	if err != nil {
+		return err
+	}
+
+	err = db.Update(func(tx *buntdb.Tx) error {
+		_, _, err := tx.Set("wclogs-tracked-characters:"+guildID, string(bytes), nil)
+		if err != nil {
+			return err
+		}
+
+		references, err := countWCLogsCharacterReferences(tx)
+		if err != nil {
+			return err
+		}
+
+		for _, charID := range removedCharIDs {
+			if references[charID] > 0 {
+				continue
+			}
+
+			if err = deleteWCLogsCharacterData(tx, charID); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	return err
+}
+
+// collectOrphanedWCLogsCharacterData deletes per-character data of characters no guild tracks anymore
+func collectOrphanedWCLogsCharacterData(db *buntdb.DB) ([]int, error) {
+	var orphans []int
+	err := db.Update(func(tx *buntdb.Tx) error {
+		var err error
+		orphans, err = findOrphanedWCLogsCharacterIDs(tx)
+		if err != nil {
+			return err
+		}
+
+		for _, charID := range orphans {
+			if err = deleteWCLogsCharacterData(tx, charID); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return orphans, nil
+}
+
+// countWCLogsCharacterReferences returns the number of guild rosters tracking each character ID
+func countWCLogsCharacterReferences(tx *buntdb.Tx) (map[int]int, error) {
+	references := make(map[int]int)
+	var err error
+	ascendErr := tx.AscendKeys("wclogs-tracked-characters:*", func(key, value string) bool {
+		var characters []*TrackedCharacter
+		if err = json.Unmarshal([]byte(value), &characters); err != nil {
+			return false
+		}
+
+		for _, c := range characters {
+			references[c.ID]++
+		}
+		return true
+	})
+	if ascendErr != nil {
+		return nil, ascendErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return references, nil
+}
+
+// findOrphanedWCLogsCharacterIDs returns character IDs with stored data but no guild tracking them
+func findOrphanedWCLogsCharacterIDs(tx *buntdb.Tx) ([]int, error) {
+	references, err := countWCLogsCharacterReferences(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	found := make(map[int]bool)
+	var orphans []int
+	for _, pattern := range []string{"wclogs-parses:*", "wclogs-latest-report:*"} {
+		err = tx.AscendKeys(pattern, func(key, value string) bool {
+			charID, err := strconv.Atoi(key[strings.LastIndex(key, ":")+1:])
+			if err != nil || found[charID] || references[charID] > 0 {
+				return true
+			}
+
+			found[charID] = true
+			orphans = append(orphans, charID)
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return orphans, nil
+}
+
+// deleteWCLogsCharacterData deletes parses and latest report of a character ID, ignoring missing keys
+func deleteWCLogsCharacterData(tx *buntdb.Tx, charID int) error {
+	for _, key := range []string{"wclogs-parses:" + strconv.Itoa(charID), "wclogs-latest-report:" + strconv.Itoa(charID)} {
+		if _, err := tx.Delete(key); err != nil && err != buntdb.ErrNotFound {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// guildKeyPrefixes lists key prefixes followed by a guild ID, channel webhooks keys continue with a channel ID
+var guildKeyPrefixes = []string{"guild-settings:", "wclogs-creds:", "wclogs-tracked-characters:", "discord-webhook:"}
+
+// listGuildIDs returns every guild ID with stored data, sorted
+func listGuildIDs(db *buntdb.DB) ([]string, error) {
+	found := make(map[string]bool)
+	err := db.View(func(tx *buntdb.Tx) error {
+		for _, prefix := range guildKeyPrefixes {
+			err := tx.AscendKeys(prefix+"*", func(key, value string) bool {
+				guildID := strings.SplitN(strings.TrimPrefix(key, prefix), ":", 2)[0]
+				found[guildID] = true
+				return true
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	guildIDs := make([]string, 0, len(found))
+	for guildID := range found {
+		guildIDs = append(guildIDs, guildID)
+	}
+	sort.Strings(guildIDs)
+
+	return guildIDs, nil
+}
+
+// fetchChannelWebhooks returns every bot webhook of a guild by channel ID
+func fetchChannelWebhooks(db *buntdb.DB, guildID string) (map[string]*ChannelWebhook, error) {
+	prefix := "discord-webhook:" + guildID + ":"
+	webhooks := make(map[string]*ChannelWebhook)
+	var err error
+	viewErr := db.View(func(tx *buntdb.Tx) error {
+		return tx.AscendKeys(prefix+"*", func(key, value string) bool {
+			var webhook ChannelWebhook
+			if err = json.Unmarshal([]byte(value), &webhook); err != nil {
+				return false
+			}
+
+			webhooks[strings.TrimPrefix(key, prefix)] = &webhook
+			return true
+		})
+	})
+	if viewErr != nil {
+		return nil, viewErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+// deleteGuildData deletes every stored data of a guild in a single transaction, including per-character data
+// of characters no other guild tracks, whose IDs are returned
+func deleteGuildData(db *buntdb.DB, guildID string) ([]int, error) {
+	var deletedCharIDs []int
+	err := db.Update(func(tx *buntdb.Tx) error {
+		var characters []*TrackedCharacter
+		val, err := tx.Get("wclogs-tracked-characters:" + guildID)
+		if err == nil {
+			err = json.Unmarshal([]byte(val), &characters)
+		}
+		if err != nil && err != buntdb.ErrNotFound {
+			return err
+		}
+
+		keys := []string{"guild-settings:" + guildID, "wclogs-creds:" + guildID, "wclogs-tracked-characters:" + guildID}
+		err = tx.AscendKeys("discord-webhook:"+guildID+":*", func(key, value string) bool {
+			keys = append(keys, key)
+			return true
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, key := range keys {
+			if _, err = tx.Delete(key); err != nil && err != buntdb.ErrNotFound {
+				return err
+			}
+		}
+
+		references, err := countWCLogsCharacterReferences(tx)
+		if err != nil {
+			return err
+		}
+
+		for _, c := range characters {
+			if references[c.ID] > 0 {
+				continue
+			}
+
+			if err = deleteWCLogsCharacterData(tx, c.ID); err != nil {
+				return err
+			}
+			deletedCharIDs = append(deletedCharIDs, c.ID)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return deletedCharIDs, nil
+}
+
+// findDatabaseInconsistencies returns a description of each inconsistency found in stored data
+func findDatabaseInconsistencies(db *buntdb.DB) ([]string, error) {
+	var issues []string
+
+	dbVersion, err := fetchDatabaseVersion(db)
+	if err != nil {
+		return nil, err
+	}
+	if dbVersion != currentDatabaseVersion {
+		issues = append(issues, fmt.Sprintf("database version is %d, expected %d", dbVersion, currentDatabaseVersion))
+	}
+
+	guildIDs, err := listGuildIDs(db)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.View(func(tx *buntdb.Tx) error {
+		orphans, err := findOrphanedWCLogsCharacterIDs(tx)
+		if err != nil {
+			return err
+		}
+		for _, charID := range orphans {
+			issues = append(issues, fmt.Sprintf("character %d has stored data but no guild tracks it", charID))
+		}
+
+		for _, guildID := range guildIDs {
+			val, err := tx.Get("wclogs-tracked-characters:" + guildID)
+			if err == buntdb.ErrNotFound {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+
+			if _, err = tx.Get("wclogs-creds:" + guildID); err == buntdb.ErrNotFound {
+				issues = append(issues, fmt.Sprintf("guild %s tracks characters without WarcraftLogs credentials", guildID))
+			}
+
+			var characters []*TrackedCharacter
+			if err = json.Unmarshal([]byte(val), &characters); err != nil {
+				issues = append(issues, fmt.Sprintf("guild %s tracked characters cannot be decoded: %s", guildID, err))
+				continue
+			}
+
+			for _, c := range characters {
+				for _, prefix := range []string{"wclogs-parses:", "wclogs-latest-report:"} {
+					if _, err = tx.Get(prefix + strconv.Itoa(c.ID)); err == buntdb.ErrNotFound {
+						issues = append(issues, fmt.Sprintf("guild %s tracks character %d (%s) without %s data",
+							guildID, c.ID, c.Slug(), strings.TrimSuffix(prefix, ":")))
+					}
+				}
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return issues, nil
+}
+
 func fetchWCLogsParsesForCharacterID(db *buntdb.DB, charID int) (*wclogs.Parses, error) {
 	var parses wclogs.Parses
 	err := db.View(func(tx *buntdb.Tx) error {
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/zergrael/epa/wclogs"
+)
+
+// maxEmbedsPerMessage is the Discord limit of embeds in a single message
+const maxEmbedsPerMessage = 10
+
+// maxFieldsPerEmbed is the Discord limit of fields in a single embed
+const maxFieldsPerEmbed = 25
+
+// maxEmbedsLength is the Discord limit of characters over every embed of a single message
+const maxEmbedsLength = 6000
+
+// trackedCharactersPerPage is the count of characters listed on each /list-tracked-characters page
+const trackedCharactersPerPage = 10
+
+// rankPercentTiers maps RankPercent floors to WarcraftLogs parse colors, from the highest floor
+var rankPercentTiers = []struct {
+	floor float64
+	color int
+	emoji string
+}{
+	{100, 0xe5cc80, ":yellow_square:"},
+	{99, 0xe268a8, ":cherry_blossom:"},
+	{95, 0xff8000, ":orange_square:"},
+	{75, 0xa335ee, ":purple_square:"},
+	{50, 0x0070ff, ":blue_square:"},
+	{25, 0x1eff00, ":green_square:"},
+	{0, 0x666666, ":white_large_square:"},
+}
+
+// rankPercentTier returns the WarcraftLogs color and matching emoji of a RankPercent
+func rankPercentTier(rankPercent float64) (int, string) {
+	for _, tier := range rankPercentTiers {
+		if rankPercent >= tier.floor {
+			return tier.color, tier.emoji
+		}
+	}
+
+	last := rankPercentTiers[len(rankPercentTiers)-1]
+	return last.color, last.emoji
+}
+
+// parsesEmbeds renders an embed per zone with the latest partition parses of a character
+// Embed color is the color of the best parse in the zone
+func parsesEmbeds(w *wclogs.WCLogs, char *wclogs.Character, parses *wclogs.Parses, lang Lang) []*discordgo.MessageEmbed {
+	if w != nil {
+		// Legacy rankings are shown under their difficulty
+		parses.ResolveUnknownDifficulty(w.Zones())
+	}
+
+	var zoneIDs []wclogs.ZoneID
+	for zoneID := range *parses {
+		zoneIDs = append(zoneIDs, zoneID)
+	}
+	// Most recent zones first
+	sort.Slice(zoneIDs, func(a, b int) bool { return zoneIDs[a] > zoneIDs[b] })
+
+	// Room is kept to tell parses were left out
+	truncatedNote := tr(lang, "parses.truncated")
+	remaining := maxEmbedsLength - utf8.RuneCountInString(truncatedNote) - 1
+	truncated := false
+
+	var embeds []*discordgo.MessageEmbed
+	for _, zoneID := range zoneIDs {
+		var zone *wclogs.Zone
+		if w != nil {
+			zone = w.GetZone(zoneID)
+		}
+		embed := zoneParsesEmbed(zoneID, zone, char, parses, lang)
+		if embed == nil {
+			continue
+		}
+		if len(embeds) >= maxEmbedsPerMessage {
+			truncated = true
+			break
+		}
+
+		for len(embed.Fields) > 0 && embedLength(embed) > remaining {
+			embed.Fields = embed.Fields[:len(embed.Fields)-1]
+			truncated = true
+		}
+		if len(embed.Fields) == 0 {
+			break
+		}
+
+		remaining -= embedLength(embed)
+		embeds = append(embeds, embed)
+		if truncated {
+			break
+		}
+	}
+
+	if truncated && len(embeds) > 0 {
+		footer := embeds[len(embeds)-1].Footer
+		footer.Text += "\n" + truncatedNote
+	}
+
+	if len(embeds) == 0 {
+		embeds = append(embeds, &discordgo.MessageEmbed{
+			Type:        discordgo.EmbedTypeRich,
+			Title:       tr(lang, "parses.title", char.Slug()),
+			Description: tr(lang, "parses.no-ranking"),
+		})
+	}
+
+	return embeds
+}
+
+// embedLength returns the count of characters Discord counts against maxEmbedsLength
+func embedLength(embed *discordgo.MessageEmbed) int {
+	length := utf8.RuneCountInString(embed.Title) + utf8.RuneCountInString(embed.Description)
+	for _, field := range embed.Fields {
+		length += utf8.RuneCountInString(field.Name) + utf8.RuneCountInString(field.Value)
+	}
+	if embed.Footer != nil {
+		length += utf8.RuneCountInString(embed.Footer.Text)
+	}
+	if embed.Author != nil {
+		length += utf8.RuneCountInString(embed.Author.Name)
+	}
+
+	return length
+}
+
+// zoneParsesEmbed renders latest partition parses of a zone, nil if there is no ranked fight in it
+func zoneParsesEmbed(zoneID wclogs.ZoneID, zone *wclogs.Zone, char *wclogs.Character, parses *wclogs.Parses, lang Lang) *discordgo.MessageEmbed {
+	partition, ok := parses.LatestPartition(zoneID)
+	if !ok {
+		return nil
+	}
+
+	zoneName := tr(lang, "parses.zone", zoneID)
+	if zone != nil {
+		zoneName = zone.Name
+	}
+
+	difficultyRankings := (*parses)[zoneID][partition]
+	var difficulties []wclogs.Difficulty
+	for difficulty := range difficultyRankings {
+		difficulties = append(difficulties, difficulty)
+	}
+	sort.Slice(difficulties, func(a, b int) bool { return difficulties[a] < difficulties[b] })
+
+	best := 0.0
+	var fields []*discordgo.MessageEmbedField
+	for _, difficulty := range difficulties {
+		var sizes []wclogs.RaidSize
+		for size := range difficultyRankings[difficulty] {
+			sizes = append(sizes, size)
+		}
+		sort.Slice(sizes, func(a, b int) bool { return sizes[a] < sizes[b] })
+
+		for _, size := range sizes {
+			metricRankings := difficultyRankings[difficulty][size]
+			for _, metric := range []wclogs.Metric{wclogs.MetricDPS, wclogs.MetricHPS} {
+				var lines []string
+				for _, ranking := range metricRankings[metric].Rankings {
+					if ranking.RankPercent <= 0 {
+						continue
+					}
+					if ranking.RankPercent > best {
+						best = ranking.RankPercent
+					}
+
+					_, emoji := rankPercentTier(ranking.RankPercent)
+					lines = append(lines, fmt.Sprintf("%s **%.2f** %s", emoji, ranking.RankPercent, ranking.Encounter.Name))
+				}
+
+				if len(lines) == 0 || len(fields) >= maxFieldsPerEmbed {
+					continue
+				}
+
+				fields = append(fields, &discordgo.MessageEmbedField{
+					Name:   fmt.Sprintf("%d %s %s", size, zone.DifficultyName(difficulty), strings.ToUpper(string(metric))),
+					Value:  strings.Join(lines, "\n"),
+					Inline: true,
+				})
+			}
+		}
+	}
+
+	if len(fields) == 0 {
+		return nil
+	}
+
+	color, _ := rankPercentTier(best)
+	return &discordgo.MessageEmbed{
+		Type:   discordgo.EmbedTypeRich,
+		Title:  fmt.Sprintf("%s - %s", char.Slug(), zoneName),
+		Color:  color,
+		Fields: fields,
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: tr(lang, "parses.partition", partition),
+		},
+	}
+}
+
+// trackedCharactersPage renders a page of tracked characters, with previous and next page buttons if needed
+func trackedCharactersPage(chars []TrackedCharacterActivity, page int, lang Lang) *discordgo.InteractionResponseData {
+	pages := (len(chars) + trackedCharactersPerPage - 1) / trackedCharactersPerPage
+	if pages < 1 {
+		pages = 1
+	}
+	if page >= pages {
+		page = pages - 1
+	}
+	if page < 0 {
+		page = 0
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Type:  discordgo.EmbedTypeRich,
+		Title: tr(lang, "list.title", len(chars)),
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: tr(lang, "list.page", page+1, pages),
+		},
+	}
+
+	if len(chars) == 0 {
+		embed.Description = tr(lang, "list.empty")
+	}
+
+	start := page * trackedCharactersPerPage
+	end := start + trackedCharactersPerPage
+	if end > len(chars) {
+		end = len(chars)
+	}
+	for _, char := range chars[start:end] {
+		report := tr(lang, "list.no-report")
+		if char.LatestReport != nil {
+			report = tr(lang, "list.report", char.LatestReport.Code, reportsURL+char.LatestReport.Code,
+				fmt.Sprintf("<t:%d:R>", char.LatestReport.EndTime.Unix()))
+		}
+
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:  char.Slug(),
+			Value: tr(lang, "list.channel", char.ClassName(), char.ChannelID) + "\n" + report,
+		})
+	}
+
+	data := &discordgo.InteractionResponseData{
+		Embeds: []*discordgo.MessageEmbed{embed},
+	}
+
+	if pages > 1 {
+		data.Components = []discordgo.MessageComponent{
+			discordgo.ActionsRow{
+				Components: []discordgo.MessageComponent{
+					discordgo.Button{
+						Label:    tr(lang, "button.previous"),
+						Style:    discordgo.SecondaryButton,
+						CustomID: trackedCharactersPageComponentID(page - 1),
+						Disabled: page == 0,
+					},
+					discordgo.Button{
+						Label:    tr(lang, "button.next"),
+						Style:    discordgo.SecondaryButton,
+						CustomID: trackedCharactersPageComponentID(page + 1),
+						Disabled: page == pages-1,
+					},
+				},
+			},
+		}
+	}
+
+	return data
+}
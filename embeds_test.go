@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/zergrael/epa/wclogs"
+)
+
+// rankedParsesFixture returns parses with every encounter ranked in zones, difficulties and sizes
+func rankedParsesFixture(zones, encounters int) *wclogs.Parses {
+	parses := make(wclogs.Parses)
+	for zoneID := wclogs.ZoneID(1); zoneID <= wclogs.ZoneID(zones); zoneID++ {
+		for _, difficulty := range []wclogs.Difficulty{3, 4} {
+			for _, size := range []wclogs.RaidSize{10, 25} {
+				metricRankings := make(wclogs.MetricRankings)
+				for _, metric := range []wclogs.Metric{wclogs.MetricDPS, wclogs.MetricHPS} {
+					var rankings []wclogs.Ranking
+					for id := 1; id <= encounters; id++ {
+						ranking := wclogs.Ranking{RankPercent: 99.99}
+						ranking.Encounter.ID = id
+						ranking.Encounter.Name = fmt.Sprintf("Encounter with a rather long name %d", id)
+						rankings = append(rankings, ranking)
+					}
+					metricRankings[metric] = wclogs.PartitionRankings{Partition: 1, Rankings: rankings}
+				}
+				parses.MergeMetricRankings(zoneID, difficulty, size, &metricRankings)
+			}
+		}
+	}
+
+	return &parses
+}
+
+func TestParsesEmbedsLength(t *testing.T) {
+	char := &wclogs.Character{Name: "Foo", Server: "Bar", Region: "EU"}
+	truncatedNote := tr(LangEnglish, "parses.truncated")
+
+	tests := []struct {
+		name          string
+		zones         int
+		encounters    int
+		wantTruncated bool
+	}{
+		{name: "single zone", zones: 1, encounters: 2},
+		{name: "icecrown citadel", zones: 1, encounters: 12, wantTruncated: true},
+		{name: "every zone", zones: 12, encounters: 12, wantTruncated: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			embeds := parsesEmbeds(nil, char, rankedParsesFixture(tt.zones, tt.encounters), LangEnglish)
+			if len(embeds) == 0 || len(embeds) > maxEmbedsPerMessage {
+				t.Fatalf("got %d embeds, want between 1 and %d", len(embeds), maxEmbedsPerMessage)
+			}
+
+			length := 0
+			for _, embed := range embeds {
+				if len(embed.Fields) == 0 || len(embed.Fields) > maxFieldsPerEmbed {
+					t.Errorf("embed %q has %d fields", embed.Title, len(embed.Fields))
+				}
+				length += embedLength(embed)
+			}
+			if length > maxEmbedsLength {
+				t.Errorf("embeds length = %d, want at most %d", length, maxEmbedsLength)
+			}
+
+			footer := embeds[len(embeds)-1].Footer.Text
+			if truncated := strings.Contains(footer, truncatedNote); truncated != tt.wantTruncated {
+				t.Errorf("footer = %q, want truncated %v", footer, tt.wantTruncated)
+			}
+		})
+	}
+}
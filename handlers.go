@@ -32,8 +32,13 @@ func guildDelete(_ *discordgo.Session, guild *discordgo.GuildDelete) {
 	destroyWCLogsForGuild(guild.ID)
 }
 
-func commandsHandler(s *discordgo.Session, interaction *discordgo.InteractionCreate) {
-	if commandFunc, ok := commandsHandlers[interaction.ApplicationCommandData().Name]; ok {
-		commandFunc(s, interaction)
+func interactionsHandler(s *discordgo.Session, interaction *discordgo.InteractionCreate) {
+	switch interaction.Type {
+	case discordgo.InteractionApplicationCommand:
+		if commandFunc, ok := commandsHandlers[interaction.ApplicationCommandData().Name]; ok {
+			commandFunc(s, interaction)
+		}
+	case discordgo.InteractionMessageComponent:
+		componentsHandler(s, interaction)
 	}
 }
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/tidwall/buntdb"
+	"github.com/zergrael/epa/wclogs"
+)
+
+// tickStaleAfter returns the delay after which a guild ticker without any completed tick is considered wedged
+// Ticks are at most maxPollInterval apart, the margin lets a tick complete while a request exhausts its retries
+func tickStaleAfter() time.Duration {
+	return maxPollInterval + wclogs.MaxRequestDuration()
+}
+
+// lastTicks contains the last completed tick time of each running guild ticker
+var lastTicks = struct {
+	sync.RWMutex
+	m map[string]time.Time
+}{m: make(map[string]time.Time)}
+
+// recordTick marks a guild ticker as alive, also used when a ticker starts
+func recordTick(guildID string) {
+	lastTicks.Lock()
+	defer lastTicks.Unlock()
+	lastTicks.m[guildID] = time.Now()
+}
+
+// forgetTick stops monitoring a guild ticker
+func forgetTick(guildID string) {
+	lastTicks.Lock()
+	defer lastTicks.Unlock()
+	delete(lastTicks.m, guildID)
+}
+
+// healthStatus is the JSON body of health endpoints
+type healthStatus struct {
+	Healthy bool              `json:"healthy"`
+	Checks  map[string]string `json:"checks"`
+}
+
+// checkSession returns an error message if discord session is not ready
+func checkSession() string {
+	if s == nil {
+		return "session not created"
+	}
+
+	s.RLock()
+	defer s.RUnlock()
+	if !s.DataReady {
+		return "session not ready"
+	}
+
+	return ""
+}
+
+// checkDatabase returns an error message if database cannot be read
+func checkDatabase() string {
+	if db == nil {
+		return "database not opened"
+	}
+
+	err := db.View(func(tx *buntdb.Tx) error {
+		_, err := tx.Get("version")
+		if err == buntdb.ErrNotFound {
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return err.Error()
+	}
+
+	return ""
+}
+
+// checkTicks returns an error message for each guild ticker without a recent tick
+func checkTicks() map[string]string {
+	lastTicks.RLock()
+	defer lastTicks.RUnlock()
+
+	failures := make(map[string]string)
+	for guildID, lastTick := range lastTicks.m {
+		if since := time.Since(lastTick); since > tickStaleAfter() {
+			failures["tick:"+guildID] = "no tick since " + since.Round(time.Second).String()
+		}
+	}
+
+	return failures
+}
+
+// writeHealthStatus writes checks results, any failed check results in a 503 status code
+func writeHealthStatus(w http.ResponseWriter, checks map[string]string) {
+	status := healthStatus{Healthy: true, Checks: make(map[string]string)}
+	for name, failure := range checks {
+		if failure == "" {
+			status.Checks[name] = "ok"
+			continue
+		}
+
+		status.Healthy = false
+		status.Checks[name] = failure
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if !status.Healthy {
+		log.Warn().Interface("checks", status.Checks).Msg("Health check failed")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		log.Error().Err(err).Msg("Failed to write health status")
+	}
+}
+
+// healthzHandler reports liveness: discord session, database and guild tickers must all be working
+func healthzHandler(w http.ResponseWriter, _ *http.Request) {
+	checks := map[string]string{
+		"session":  checkSession(),
+		"database": checkDatabase(),
+	}
+	for name, failure := range checkTicks() {
+		checks[name] = failure
+	}
+
+	writeHealthStatus(w, checks)
+}
+
+// readyzHandler reports readiness: discord session and database must be available
+func readyzHandler(w http.ResponseWriter, _ *http.Request) {
+	writeHealthStatus(w, map[string]string{
+		"session":  checkSession(),
+		"database": checkDatabase(),
+	})
+}
@@ -0,0 +1,440 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/rs/zerolog/log"
+)
+
+// Lang is a language bot responses and announcements are translated to
+type Lang string
+
+const (
+	LangEnglish Lang = "en"
+	LangFrench  Lang = "fr"
+)
+
+// defaultLang is used when neither the member nor the guild language is supported
+const defaultLang = LangEnglish
+
+// langNames contains each supported Lang name, written in that language
+var langNames = map[Lang]string{
+	LangEnglish: "English",
+	LangFrench:  "Français",
+}
+
+// messages is the catalogue of translated messages by key, every key must be translated in defaultLang
+var messages = map[string]map[Lang]string{
+	"epa.hello": {
+		LangEnglish: "Hello there",
+		LangFrench:  "Bonjour",
+	},
+	"epa.running": {
+		LangEnglish: "WarcraftLogs engine is running, currently tracking %d characters, see /track-character command to add more.",
+		LangFrench:  "Le suivi WarcraftLogs est actif avec %d personnages, voir la commande /suivre-personnage pour en ajouter.",
+	},
+	"epa.disabled": {
+		LangEnglish: "WarcraftLogs is disabled, see /register-warcraftlogs command as an admin",
+		LangFrench:  "WarcraftLogs est désactivé, voir la commande /enregistrer-warcraftlogs en tant qu'admin",
+	},
+	"wclogs.missing": {
+		LangEnglish: "Missing WarcraftLogs credentials setup",
+		LangFrench:  "Les identifiants WarcraftLogs ne sont pas configurés",
+	},
+	"wclogs.rate-limited": {
+		LangEnglish: "WarcraftLogs API rate limit reached, please retry later",
+		LangFrench:  "Limite d'utilisation de l'API WarcraftLogs atteinte, merci de réessayer plus tard",
+	},
+	"wclogs.unauthorized": {
+		LangEnglish: "WarcraftLogs API credentials were rejected, see /register-warcraftlogs command as an admin",
+		LangFrench:  "Les identifiants d'API WarcraftLogs ont été refusés, voir la commande /enregistrer-warcraftlogs en tant qu'admin",
+	},
+	"wclogs.unavailable": {
+		LangEnglish: "WarcraftLogs is currently unavailable, please retry later",
+		LangFrench:  "WarcraftLogs est actuellement indisponible, merci de réessayer plus tard",
+	},
+	"wclogs.disabled": {
+		LangEnglish: "WarcraftLogs API credentials were rejected, characters are not tracked anymore. An admin needs to run /register-warcraftlogs again with valid credentials",
+		LangFrench:  "Les identifiants d'API WarcraftLogs ont été refusés, les personnages ne sont plus suivis. Un admin doit relancer la commande /enregistrer-warcraftlogs avec des identifiants valides",
+	},
+	"register.invalid": {
+		LangEnglish: "These API credentials cannot be used",
+		LangFrench:  "Ces identifiants d'API ne sont pas utilisables",
+	},
+	"register.store-failed": {
+		LangEnglish: "API credentials are valid, but I failed to store them",
+		LangFrench:  "Les identifiants d'API sont valides, mais leur enregistrement a échoué",
+	},
+	"register.success": {
+		LangEnglish: "Congrats, API credentials are valid",
+		LangFrench:  "Bravo, les identifiants d'API sont valides",
+	},
+	"unregister.missing": {
+		LangEnglish: "No stored credentials",
+		LangFrench:  "Aucun identifiant enregistré",
+	},
+	"unregister.success": {
+		LangEnglish: "Unregister successful",
+		LangFrench:  "Identifiants supprimés",
+	},
+	"track.not-found": {
+		LangEnglish: "Failed to track %s : character not found !",
+		LangFrench:  "Impossible de suivre %s : personnage introuvable !",
+	},
+	"track.no-report": {
+		LangEnglish: "Failed to track %s : no recent report",
+		LangFrench:  "Impossible de suivre %s : aucun rapport récent",
+	},
+	"track.failed": {
+		LangEnglish: "Failed to track %s",
+		LangFrench:  "Impossible de suivre %s",
+	},
+	"track.success": {
+		LangEnglish: "%s is now tracked",
+		LangFrench:  "%s est maintenant suivi",
+	},
+	"untrack.not-found": {
+		LangEnglish: "Failed to untrack %s : character not found !",
+		LangFrench:  "Impossible d'arrêter le suivi de %s : personnage introuvable !",
+	},
+	"untrack.not-tracked": {
+		LangEnglish: "%s was not tracked",
+		LangFrench:  "%s n'était pas suivi",
+	},
+	"untrack.failed": {
+		LangEnglish: "Failed to untrack %s",
+		LangFrench:  "Impossible d'arrêter le suivi de %s",
+	},
+	"untrack.success": {
+		LangEnglish: "%s is not tracked anymore",
+		LangFrench:  "%s n'est plus suivi",
+	},
+	"untrack.gone": {
+		LangEnglish: "This character is not tracked anymore",
+		LangFrench:  "Ce personnage n'est plus suivi",
+	},
+	"parses.not-found": {
+		LangEnglish: "Failed to find %s : character not found !",
+		LangFrench:  "Impossible de trouver %s : personnage introuvable !",
+	},
+	"parses.failed": {
+		LangEnglish: "Failed to get parses for %s",
+		LangFrench:  "Impossible de récupérer les parses de %s",
+	},
+	"parses.none-stored": {
+		LangEnglish: "No known parses for %s",
+		LangFrench:  "Aucun parse connu pour %s",
+	},
+	"parses.title": {
+		LangEnglish: "Parses for %s",
+		LangFrench:  "Parses de %s",
+	},
+	"parses.no-ranking": {
+		LangEnglish: "No ranked fight yet",
+		LangFrench:  "Aucun combat classé pour le moment",
+	},
+	"parses.zone": {
+		LangEnglish: "Zone %d",
+		LangFrench:  "Zone %d",
+	},
+	"parses.partition": {
+		LangEnglish: "Partition %d",
+		LangFrench:  "Partition %d",
+	},
+	"parses.truncated": {
+		LangEnglish: "Some parses are not shown, Discord limits the size of a message",
+		LangFrench:  "Certains parses ne sont pas affichés, Discord limite la taille d'un message",
+	},
+	"manager.denied": {
+		LangEnglish: "Only members with the <@&%s> role or the Manage Server permission can change tracked characters",
+		LangFrench:  "Seuls les membres avec le rôle <@&%s> ou la permission Gérer le serveur peuvent modifier les personnages suivis",
+	},
+	"manager.everyone": {
+		LangEnglish: "Everyone can now change tracked characters",
+		LangFrench:  "Tout le monde peut maintenant modifier les personnages suivis",
+	},
+	"manager.role": {
+		LangEnglish: "Only <@&%s> members can now change tracked characters",
+		LangFrench:  "Seuls les membres <@&%s> peuvent maintenant modifier les personnages suivis",
+	},
+	"settings.read-failed": {
+		LangEnglish: "Failed to read guild settings",
+		LangFrench:  "Impossible de lire les paramètres du serveur",
+	},
+	"settings.store-failed": {
+		LangEnglish: "Failed to store guild settings",
+		LangFrench:  "Impossible d'enregistrer les paramètres du serveur",
+	},
+	"language.set": {
+		LangEnglish: "Announcements are now sent in English",
+		LangFrench:  "Les annonces sont maintenant envoyées en français",
+	},
+	"webhook.enabled": {
+		LangEnglish: "Announcements are now sent under character names, the bot needs the Manage Webhooks permission",
+		LangFrench:  "Les annonces sont maintenant envoyées au nom des personnages, le bot a besoin de la permission Gérer les webhooks",
+	},
+	"webhook.disabled": {
+		LangEnglish: "Announcements are now sent by the bot",
+		LangFrench:  "Les annonces sont maintenant envoyées par le bot",
+	},
+	"notification.invalid-url": {
+		LangEnglish: "Webhook URL must be an absolute https URL to a public address",
+		LangFrench:  "L'URL du webhook doit être une URL https complète vers une adresse publique",
+	},
+	"notification.exists": {
+		LangEnglish: "This webhook already receives events",
+		LangFrench:  "Ce webhook reçoit déjà les événements",
+	},
+	"notification.added": {
+		LangEnglish: "This webhook now receives events",
+		LangFrench:  "Ce webhook reçoit maintenant les événements",
+	},
+	"notification.unknown": {
+		LangEnglish: "This webhook does not receive events",
+		LangFrench:  "Ce webhook ne reçoit pas les événements",
+	},
+	"notification.removed": {
+		LangEnglish: "This webhook does not receive events anymore",
+		LangFrench:  "Ce webhook ne reçoit plus les événements",
+	},
+	"notification.none": {
+		LangEnglish: "No webhook receives events",
+		LangFrench:  "Aucun webhook ne reçoit les événements",
+	},
+	"notification.list": {
+		LangEnglish: "Webhooks receiving events :",
+		LangFrench:  "Webhooks recevant les événements :",
+	},
+	"list.title": {
+		LangEnglish: "Tracked characters (%d)",
+		LangFrench:  "Personnages suivis (%d)",
+	},
+	"list.page": {
+		LangEnglish: "Page %d/%d",
+		LangFrench:  "Page %d/%d",
+	},
+	"list.empty": {
+		LangEnglish: "No tracked character yet, see /track-character command",
+		LangFrench:  "Aucun personnage suivi pour le moment, voir la commande /suivre-personnage",
+	},
+	"list.channel": {
+		LangEnglish: "%s in <#%s>",
+		LangFrench:  "%s dans <#%s>",
+	},
+	"list.report": {
+		LangEnglish: "Report [%s](%s) %s",
+		LangFrench:  "Rapport [%s](%s) %s",
+	},
+	"list.no-report": {
+		LangEnglish: "No known report",
+		LangFrench:  "Aucun rapport connu",
+	},
+	"button.previous": {
+		LangEnglish: "Previous",
+		LangFrench:  "Précédent",
+	},
+	"button.next": {
+		LangEnglish: "Next",
+		LangFrench:  "Suivant",
+	},
+	"button.show-parses": {
+		LangEnglish: "Show all parses",
+		LangFrench:  "Voir tous les parses",
+	},
+	"button.untrack": {
+		LangEnglish: "Untrack",
+		LangFrench:  "Ne plus suivre",
+	},
+	"button.open-report": {
+		LangEnglish: "Open report",
+		LangFrench:  "Ouvrir le rapport",
+	},
+	"button.open-fight": {
+		LangEnglish: "Open fight",
+		LangFrench:  "Ouvrir le combat",
+	},
+	"announce.new-report": {
+		LangEnglish: "New report found",
+		LangFrench:  "Nouveau rapport trouvé",
+	},
+	"announce.new-partition": {
+		LangEnglish: "New rankings partition for %s",
+		LangFrench:  "Nouvelle partition de classement pour %s",
+	},
+	"announce.new-partition-description": {
+		LangEnglish: "Parses are now compared against partition %d only\n%s",
+		LangFrench:  "Les parses sont maintenant comparés à la partition %d uniquement\n%s",
+	},
+	"announce.unknown-zone": {
+		LangEnglish: "Unknown zone",
+		LangFrench:  "Zone inconnue",
+	},
+	"announce.first-kill": {
+		LangEnglish: "First %s kill for %s",
+		LangFrench:  "Premier kill de %s pour %s",
+	},
+	"announce.new-parse": {
+		LangEnglish: "New parse for %s",
+		LangFrench:  "Nouveau parse pour %s",
+	},
+}
+
+// tr returns the key message translated to lang and formatted with args, falling back to defaultLang
+func tr(lang Lang, key string, args ...interface{}) string {
+	translations, ok := messages[key]
+	if !ok {
+		log.Error().Str("key", key).Msg("Unknown message key")
+		return key
+	}
+
+	message, ok := translations[lang]
+	if !ok {
+		message = translations[defaultLang]
+	}
+
+	if len(args) == 0 {
+		return message
+	}
+
+	return fmt.Sprintf(message, args...)
+}
+
+// langFromLocale returns the Lang matching a Discord locale, false if that language is not supported
+func langFromLocale(locale discordgo.Locale) (Lang, bool) {
+	lang := Lang(strings.SplitN(string(locale), "-", 2)[0])
+	if _, ok := langNames[lang]; ok {
+		return lang, true
+	}
+
+	return "", false
+}
+
+// guildLang returns the guild default Lang, from guild settings then from the Discord guild locale
+func guildLang(guildID string) Lang {
+	settings, err := fetchGuildSettings(db, guildID)
+	if err != nil {
+		log.Error().Err(err).Str("guildID", guildID).Msg("fetchGuildSettings failed")
+	} else if settings.Language != "" {
+		return settings.Language
+	}
+
+	if s != nil && s.State != nil {
+		if guild, err := s.State.Guild(guildID); err == nil {
+			if lang, ok := langFromLocale(discordgo.Locale(guild.PreferredLocale)); ok {
+				return lang
+			}
+		}
+	}
+
+	return defaultLang
+}
+
+// interactionLang returns the Lang of the member client, falling back to the guild default Lang
+func interactionLang(i *discordgo.InteractionCreate) Lang {
+	if lang, ok := langFromLocale(i.Locale); ok {
+		return lang
+	}
+
+	return guildLang(i.GuildID)
+}
+
+// commandLocalization contains a command or option name and description translation
+type commandLocalization struct {
+	name        string
+	description string
+}
+
+// commandsLocalizations contains commands translations by Discord locale, options are keyed by "command/option"
+// and option choices by "command/option/value", choices only have a name
+var commandsLocalizations = map[discordgo.Locale]map[string]commandLocalization{
+	discordgo.French: {
+		"epa":                                 {"epa", "Afficher la configuration et les informations du bot"},
+		"register-warcraftlogs":               {"enregistrer-warcraftlogs", "Configurer les identifiants de l'API WarcraftLogs"},
+		"register-warcraftlogs/client-id":     {"client-id", "Client ID de l'API WCLogs"},
+		"register-warcraftlogs/client-secret": {"client-secret", "Client secret de l'API WCLogs"},
+		"unregister-warcraftlogs":             {"supprimer-warcraftlogs", "Effacer les identifiants de l'API WarcraftLogs"},
+		"set-manager-role":                    {"definir-role-gestion", "Réserver la modification des personnages suivis à un rôle, tout le monde sinon"},
+		"set-manager-role/role":               {"role", "Rôle de gestion EPA, laisser vide pour autoriser tout le monde"},
+		"set-language":                        {"definir-langue", "Choisir la langue par défaut des réponses et annonces"},
+		"set-language/language":               {"langue", "Langue par défaut"},
+		"set-language/language/en":            {"Anglais", ""},
+		"set-language/language/fr":            {"Français", ""},
+		"set-webhook-announcements":           {"definir-annonces-webhook", "Envoyer les annonces au nom des personnages avec un webhook"},
+		"set-webhook-announcements/enabled":   {"active", "Annonces au nom des personnages"},
+		"notification-webhooks":               {"webhooks-notification", "Gérer les webhooks HTTP recevant les événements en JSON"},
+		"notification-webhooks/add":           {"ajouter", "Envoyer les événements à un webhook"},
+		"notification-webhooks/add/url":       {"url", "URL du webhook"},
+		"notification-webhooks/remove":        {"retirer", "Ne plus envoyer les événements à un webhook"},
+		"notification-webhooks/remove/url":    {"url", "URL du webhook"},
+		"notification-webhooks/list":          {"lister", "Lister les webhooks recevant les événements"},
+		"track-character":                     {"suivre-personnage", "Suivre les parses WCLogs d'un personnage"},
+		"track-character/character":           {"personnage", "Nom du personnage"},
+		"track-character/server":              {"serveur", "Serveur du personnage"},
+		"track-character/region":              {"region", "Région du serveur du personnage (EU/US)"},
+		"track-character/channel":             {"salon", "Salon des annonces"},
+		"untrack-character":                   {"oublier-personnage", "Arrêter le suivi des parses WCLogs d'un personnage"},
+		"untrack-character/character":         {"personnage", "Nom du personnage"},
+		"untrack-character/server":            {"serveur", "Serveur du personnage"},
+		"untrack-character/region":            {"region", "Région du serveur du personnage (EU/US)"},
+		"parses":                              {"parses", "Afficher les parses actuels d'un personnage"},
+		"parses/character":                    {"personnage", "Nom du personnage"},
+		"parses/server":                       {"serveur", "Serveur du personnage"},
+		"parses/region":                       {"region", "Région du serveur du personnage (EU/US)"},
+		"list-tracked-characters":             {"lister-personnages-suivis", "Lister les personnages suivis"},
+	},
+}
+
+// localizeCommands fills commands and options localizations from commandsLocalizations
+func localizeCommands(commands []*discordgo.ApplicationCommand) {
+	for _, command := range commands {
+		names := make(map[discordgo.Locale]string)
+		descriptions := make(map[discordgo.Locale]string)
+		for locale, localizations := range commandsLocalizations {
+			if l, ok := localizations[command.Name]; ok {
+				names[locale] = l.name
+				descriptions[locale] = l.description
+			}
+		}
+		command.NameLocalizations = &names
+		command.DescriptionLocalizations = &descriptions
+
+		localizeOptions(command.Name, command.Options)
+	}
+}
+
+// localizeOptions fills options localizations, recursively for sub commands options
+func localizeOptions(prefix string, options []*discordgo.ApplicationCommandOption) {
+	for _, option := range options {
+		key := prefix + "/" + option.Name
+		option.NameLocalizations = make(map[discordgo.Locale]string)
+		option.DescriptionLocalizations = make(map[discordgo.Locale]string)
+		for locale, localizations := range commandsLocalizations {
+			if l, ok := localizations[key]; ok {
+				option.NameLocalizations[locale] = l.name
+				option.DescriptionLocalizations[locale] = l.description
+			}
+		}
+
+		localizeChoices(key, option.Choices)
+		localizeOptions(key, option.Options)
+	}
+}
+
+// localizeChoices fills option choices name localizations
+func localizeChoices(prefix string, choices []*discordgo.ApplicationCommandOptionChoice) {
+	for _, choice := range choices {
+		key := fmt.Sprintf("%s/%v", prefix, choice.Value)
+		choice.NameLocalizations = make(map[discordgo.Locale]string)
+		for locale, localizations := range commandsLocalizations {
+			if l, ok := localizations[key]; ok {
+				choice.NameLocalizations[locale] = l.name
+			}
+		}
+	}
+}
+
+func init() {
+	localizeCommands(commands)
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// commandsLocalizationKeys returns the commandsLocalizations key of every command, option and choice
+func commandsLocalizationKeys() []string {
+	var keys []string
+	var addOptions func(prefix string, options []*discordgo.ApplicationCommandOption)
+	addOptions = func(prefix string, options []*discordgo.ApplicationCommandOption) {
+		for _, option := range options {
+			key := prefix + "/" + option.Name
+			keys = append(keys, key)
+			for _, choice := range option.Choices {
+				keys = append(keys, fmt.Sprintf("%s/%v", key, choice.Value))
+			}
+			addOptions(key, option.Options)
+		}
+	}
+
+	for _, command := range commands {
+		keys = append(keys, command.Name)
+		addOptions(command.Name, command.Options)
+	}
+
+	return keys
+}
+
+func TestCommandsLocalizations(t *testing.T) {
+	keys := commandsLocalizationKeys()
+	for locale, localizations := range commandsLocalizations {
+		for _, key := range keys {
+			if _, ok := localizations[key]; !ok {
+				t.Errorf("%s: missing %q localization", locale, key)
+			}
+		}
+		for key := range localizations {
+			if !arrayContains(keys, key) {
+				t.Errorf("%s: %q localization matches no command, option or choice", locale, key)
+			}
+		}
+	}
+}
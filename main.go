@@ -1,10 +1,13 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
@@ -20,20 +23,103 @@ var s *discordgo.Session
 // db is global database handler
 var db *buntdb.DB
 
-// logs is WCLogs handler for each guildID
+// logs is WCLogs handler for each guildID, guarded by guildsMutex
 var logs map[string]*wclogs.WCLogs
 
-// FIXME: Global commands register slowly, stick to guild specific commands for now
-const globalCommands = false
+// botToken is Discord bot access token
+var botToken string
 
-func init() {
-	// botToken is Discord bot access token
-	var botToken string
-	flag.StringVar(&botToken, "token", lookupEnvOrString("DISCORD_BOT_TOKEN", ""), "Bot discord access token")
+// databasePath is the buntdb data file
+var databasePath string
+
+// backupDir is the directory where database snapshots are saved
+var backupDir string
+
+// backupInterval is the delay between scheduled database snapshots, 0 disables them
+var backupInterval time.Duration
+
+// backupRetention is the count of scheduled database snapshots to keep
+var backupRetention int
+
+// shrinkInterval is the delay between scheduled database compactions, 0 falls back to buntdb auto shrink
+var shrinkInterval time.Duration
+
+// httpAddr is the monitoring HTTP server listen address, empty to disable it
+var httpAddr string
+
+// shutdownTimeout bounds the wait for in-flight checks and announcements on shutdown
+var shutdownTimeout time.Duration
+
+// globalCommands registers commands for every guild at once instead of guild specific commands
+// FIXME: Global commands register slowly, stick to guild specific commands by default
+var globalCommands bool
+
+// minPollInterval and maxPollInterval bound the guild ticker duration, adjusted on the latest report age
+var minPollInterval, maxPollInterval time.Duration
+
+// parseImprovementThreshold is the minimal RankPercent improvement announced
+var parseImprovementThreshold float64
+
+// badParseThreshold is the RankPercent under which a parse is considered bad
+var badParseThreshold float64
+
+// reportsURL is the WarcraftLogs reports link prefix used in announcements
+var reportsURL string
+
+// parseFlags reads configuration file, env variables and flags, then sets up logging
+// Called from main rather than init, so that tests keep their own flags
+func parseFlags() {
+	configPath := lookupConfigPath(os.Args[1:])
+	cfg, configErr := loadConfig(configPath)
+	if configErr != nil {
+		cfg = defaultConfig()
+	}
+
+	// configPath is read before parsing flags, the flag is only declared for usage output
+	flag.String("config", configPath, "Configuration file path, also read from CONFIG_FILE env variable")
+
+	flag.StringVar(&botToken, "token", lookupEnvOrString("DISCORD_BOT_TOKEN", cfg.Token), "Bot discord access token")
 
 	// debug forces debug messages output
 	var debug bool
-	flag.BoolVar(&debug, "debug", lookupEnvOrBool("DEBUG", false), "Output debug messages")
+	flag.BoolVar(&debug, "debug", lookupEnvOrBool("DEBUG", cfg.Debug), "Output debug messages")
+
+	// logFormat is either console or json
+	var logFormat string
+	flag.StringVar(&logFormat, "log-format", lookupEnvOrString("LOG_FORMAT", cfg.LogFormat), "Log output format, console or json")
+
+	flag.StringVar(&databasePath, "db", lookupEnvOrString("DATABASE_PATH", cfg.Database.Path), "Database file path")
+	flag.StringVar(&backupDir, "backup-dir", lookupEnvOrString("BACKUP_DIR", cfg.Database.BackupDir), "Database snapshots directory")
+	flag.DurationVar(&backupInterval, "backup-interval", lookupEnvOrDuration("BACKUP_INTERVAL", cfg.Database.BackupInterval), "Delay between database snapshots, 0 to disable")
+	flag.IntVar(&backupRetention, "backup-retention", lookupEnvOrInt("BACKUP_RETENTION", cfg.Database.BackupRetention), "Count of database snapshots to keep")
+	flag.DurationVar(&shrinkInterval, "shrink-interval", lookupEnvOrDuration("SHRINK_INTERVAL", cfg.Database.ShrinkInterval), "Delay between database compactions, 0 to use automatic compaction")
+
+	flag.StringVar(&httpAddr, "http-addr", lookupEnvOrString("HTTP_ADDR", cfg.HTTPAddr), "Monitoring HTTP server listen address, empty to disable")
+	flag.DurationVar(&shutdownTimeout, "shutdown-timeout", lookupEnvOrDuration("SHUTDOWN_TIMEOUT", cfg.ShutdownTimeout), "Maximal wait for in-flight work on shutdown")
+	flag.BoolVar(&globalCommands, "global-commands", lookupEnvOrBool("GLOBAL_COMMANDS", cfg.GlobalCommands), "Register global commands instead of guild commands")
+
+	flag.DurationVar(&minPollInterval, "poll-min-interval", lookupEnvOrDuration("POLL_MIN_INTERVAL", cfg.Polling.MinInterval), "Minimal delay between WarcraftLogs checks")
+	flag.DurationVar(&maxPollInterval, "poll-max-interval", lookupEnvOrDuration("POLL_MAX_INTERVAL", cfg.Polling.MaxInterval), "Maximal delay between WarcraftLogs checks")
+
+	flag.Float64Var(&parseImprovementThreshold, "improvement-threshold", lookupEnvOrFloat("IMPROVEMENT_THRESHOLD", cfg.Announcements.ImprovementThreshold), "Minimal parse improvement announced")
+	flag.Float64Var(&badParseThreshold, "bad-parse-threshold", lookupEnvOrFloat("BAD_PARSE_THRESHOLD", cfg.Announcements.BadParseThreshold), "Parse under which announcements are mocking")
+	flag.StringVar(&reportsURL, "reports-url", lookupEnvOrString("REPORTS_URL", cfg.Announcements.ReportsURL), "WarcraftLogs reports link prefix")
+
+	flag.Usage = func() {
+		out := flag.CommandLine.Output()
+		_, _ = out.Write([]byte("Usage: epa [flags] [command]\n\nCommands:\n" +
+			"  db backup [path]\tSave a database snapshot, defaults to the backup directory\n" +
+			"  db restore <path>\tReplace the database with a snapshot, the bot must be stopped\n" +
+			"  db guilds\t\tList guilds with stored data\n" +
+			"  db dump <guildID>\tPrint a guild settings and tracked characters\n" +
+			"  db parses <charID>\tPrint a character latest report and parses\n" +
+			"  db migrate\t\tApply pending database migrations\n" +
+			"  db check [fix]\tReport inconsistencies, fix deletes orphaned character data\n" +
+			"  db delete-guild <guildID>\tDelete every data of a guild\n" +
+			"  Database commands must only run while the bot is stopped\n" +
+			"  check [flags]\t\tPreview announcements for a character, see check -h\n\nFlags:\n"))
+		flag.PrintDefaults()
+	}
 
 	flag.Parse()
 
@@ -41,8 +127,36 @@ func init() {
 	if debug {
 		level = zerolog.DebugLevel
 	}
-	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr, TimeFormat: time.RFC3339}).Level(level)
+	if logFormat == "json" {
+		log.Logger = zerolog.New(os.Stderr).With().Timestamp().Logger().Level(level)
+	} else {
+		log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr, TimeFormat: time.RFC3339}).Level(level)
+	}
 
+	if configErr != nil {
+		log.Fatal().Err(configErr).Str("path", configPath).Msg("Cannot read configuration file")
+	}
+
+	if minPollInterval <= 0 || maxPollInterval < minPollInterval {
+		log.Fatal().Dur("min", minPollInterval).Dur("max", maxPollInterval).Msg("Invalid polling interval bounds")
+	}
+
+	logs = make(map[string]*wclogs.WCLogs)
+}
+
+func main() {
+	parseFlags()
+
+	if flag.NArg() > 0 {
+		runCommand(flag.Args())
+		return
+	}
+
+	runBot()
+}
+
+// runBot starts the discord bot and blocks until interrupted
+func runBot() {
 	if botToken == "" {
 		log.Fatal().Msg("Missing --token flag / DISCORD_BOT_TOKEN env variable")
 	}
@@ -53,14 +167,9 @@ func init() {
 		log.Fatal().Err(err).Msg("Invalid bot parameters")
 	}
 
-	logs = make(map[string]*wclogs.WCLogs)
-}
-
-func main() {
 	// Database
 
-	var err error
-	db, err = buntdb.Open("storage/data.db")
+	db, err = buntdb.Open(databasePath)
 	if err != nil {
 		log.Fatal().Err(err).Msg("Cannot open database")
 	}
@@ -71,17 +180,31 @@ func main() {
 		}
 	}(db)
 
-	err = upgradeDatabaseIfNecessary(db)
+	err = upgradeDatabaseIfNecessary(db, databasePath)
 	if err != nil {
 		log.Fatal().Err(err).Msg("Failed to apply database migrations")
 	}
 
+	orphans, err := collectOrphanedWCLogsCharacterData(db)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to collect orphaned character data")
+	} else if len(orphans) > 0 {
+		log.Info().Ints("charIDs", orphans).Msg("Collected orphaned character data")
+	}
+
+	err = setupDatabaseMaintenance(db)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to setup database maintenance")
+	}
+
+	httpServer := startHTTPServer()
+
 	// Discordgo handlers
 
 	s.AddHandler(ready)
 	s.AddHandler(guildCreate)
 	s.AddHandler(guildDelete)
-	s.AddHandler(commandsHandler)
+	s.AddHandler(interactionsHandler)
 
 	s.Identify.Intents = discordgo.IntentsAllWithoutPrivileged
 
@@ -113,14 +236,32 @@ func main() {
 	// Bot run loop
 
 	stop := make(chan os.Signal, 1)
-	signal.Notify(stop, os.Interrupt)
-	<-stop
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	sig := <-stop
+
+	log.Info().Str("signal", sig.String()).Msg("Graceful shutdown")
+
+	// Stop guild tickers and scheduled maintenance, then wait for running checks and announcements
+	// WarcraftLogs requests still running after shutdownTimeout are cancelled
+	stopBot()
+	if !drainInFlight(shutdownTimeout) {
+		log.Warn().Dur("timeout", shutdownTimeout).Msg("In-flight work still running, shutting down anyway")
+	}
+	cancelRequests()
 
 	if globalCommands {
 		removeCommands("")
 	}
 
-	log.Info().Msg("Graceful shutdown")
+	if httpServer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := httpServer.Shutdown(ctx); err != nil {
+			log.Error().Err(err).Msg("Failed to properly shutdown HTTP server")
+		}
+		cancel()
+	}
+
+	// Session then database are closed by deferred calls
 }
 
 // lookupEnvOrString returns key environment variable or defaultVal
@@ -140,3 +281,39 @@ func lookupEnvOrBool(key string, defaultVal bool) bool {
 
 	return defaultVal
 }
+
+// lookupEnvOrInt returns key environment variable or defaultVal, invalid values are ignored
+func lookupEnvOrInt(key string, defaultVal int) int {
+	if val, ok := os.LookupEnv(key); ok {
+		if i, err := strconv.Atoi(val); err == nil {
+			return i
+		}
+		log.Warn().Str("key", key).Str("value", val).Msg("Ignoring invalid integer environment variable")
+	}
+
+	return defaultVal
+}
+
+// lookupEnvOrDuration returns key environment variable or defaultVal, invalid values are ignored
+func lookupEnvOrDuration(key string, defaultVal time.Duration) time.Duration {
+	if val, ok := os.LookupEnv(key); ok {
+		if d, err := time.ParseDuration(val); err == nil {
+			return d
+		}
+		log.Warn().Str("key", key).Str("value", val).Msg("Ignoring invalid duration environment variable")
+	}
+
+	return defaultVal
+}
+
+// lookupEnvOrFloat returns key environment variable or defaultVal, invalid values are ignored
+func lookupEnvOrFloat(key string, defaultVal float64) float64 {
+	if val, ok := os.LookupEnv(key); ok {
+		if f, err := strconv.ParseFloat(val, 64); err == nil {
+			return f
+		}
+		log.Warn().Str("key", key).Str("value", val).Msg("Ignoring invalid float environment variable")
+	}
+
+	return defaultVal
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/rs/zerolog/log"
+	"github.com/zergrael/epa/wclogs"
+)
+
+var (
+	ticksTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "epa_ticks_total",
+		Help: "Count of WCLogs ticker ticks per guild",
+	}, []string{"guild"})
+
+	characterChecksDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "epa_character_check_duration_seconds",
+		Help:    "Duration of checkWCLogsForCharacterUpdates per guild",
+		Buckets: prometheus.ExponentialBuckets(0.1, 2, 10),
+	}, []string{"guild"})
+
+	characterCheckErrorsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "epa_character_check_errors_total",
+		Help: "Count of failed checkWCLogsForCharacterUpdates per guild",
+	}, []string{"guild"})
+
+	wclogsRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "epa_wclogs_requests_total",
+		Help: "Count of WarcraftLogs API requests per query and status",
+	}, []string{"query", "status"})
+
+	wclogsRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "epa_wclogs_request_duration_seconds",
+		Help:    "Duration of WarcraftLogs API requests per query",
+		Buckets: prometheus.ExponentialBuckets(0.05, 2, 10),
+	}, []string{"query"})
+
+	wclogsRateLimitPointsSpent = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "epa_wclogs_rate_limit_points_spent",
+		Help: "WarcraftLogs API points spent this hour per guild",
+	}, []string{"guild"})
+
+	wclogsRateLimitPerHour = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "epa_wclogs_rate_limit_per_hour",
+		Help: "WarcraftLogs API points available per hour per guild",
+	}, []string{"guild"})
+
+	wclogsRateLimitResetSeconds = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "epa_wclogs_rate_limit_reset_seconds",
+		Help: "Seconds until WarcraftLogs API points reset per guild",
+	}, []string{"guild"})
+
+	announcementsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "epa_announcements_total",
+		Help: "Count of announcements per type and status",
+	}, []string{"type", "status"})
+
+	trackedCharactersCount = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "epa_tracked_characters",
+		Help: "Count of tracked characters per guild",
+	}, []string{"guild"})
+)
+
+// observeWCLogsRequest is a wclogs.RequestObserver recording API requests metrics
+func observeWCLogsRequest(query string, duration time.Duration, err error) {
+	status := "success"
+	if err != nil {
+		status = "error"
+	}
+
+	wclogsRequestsTotal.WithLabelValues(query, status).Inc()
+	wclogsRequestDuration.WithLabelValues(query).Observe(duration.Seconds())
+}
+
+// observeCharacterCheck records a checkWCLogsForCharacterUpdates run metrics
+func observeCharacterCheck(guildID string, duration time.Duration, err error) {
+	characterChecksDuration.WithLabelValues(guildID).Observe(duration.Seconds())
+	if err != nil {
+		characterCheckErrorsTotal.WithLabelValues(guildID).Inc()
+	}
+}
+
+// observeAnnouncement records a sent or failed announcement
+func observeAnnouncement(announcement string, err error) {
+	status := "sent"
+	if err != nil {
+		status = "failed"
+	}
+
+	announcementsTotal.WithLabelValues(announcement, status).Inc()
+}
+
+// updateRateLimitsMetrics queries current WarcraftLogs rate limits of a guild
+func updateRateLimitsMetrics(ctx context.Context, guildID string, w *wclogs.WCLogs) {
+	rateLimits, err := w.GetRateLimits(ctx)
+	if err != nil {
+		log.Warn().Err(err).Str("guildID", guildID).Msg("Failed to get rate limits")
+		return
+	}
+
+	wclogsRateLimitPointsSpent.WithLabelValues(guildID).Set(rateLimits.PointsSpentThisHour)
+	wclogsRateLimitPerHour.WithLabelValues(guildID).Set(float64(rateLimits.LimitPerHour))
+	wclogsRateLimitResetSeconds.WithLabelValues(guildID).Set(float64(rateLimits.PointsResetIn))
+}
+
+// deleteGuildMetrics removes every per-guild metric, used once a guild is not tracked anymore
+func deleteGuildMetrics(guildID string) {
+	labels := prometheus.Labels{"guild": guildID}
+	ticksTotal.Delete(labels)
+	characterChecksDuration.Delete(labels)
+	characterCheckErrorsTotal.Delete(labels)
+	wclogsRateLimitPointsSpent.Delete(labels)
+	wclogsRateLimitPerHour.Delete(labels)
+	wclogsRateLimitResetSeconds.Delete(labels)
+	trackedCharactersCount.Delete(labels)
+}
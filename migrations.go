@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/tidwall/buntdb"
+	"github.com/zergrael/epa/wclogs"
+)
+
+// migration represents a named database change, applied in a single transaction
+type migration struct {
+	name  string
+	apply func(tx *buntdb.Tx) error
+}
+
+// migrations is the ordered list of database migrations, database version is the count of applied migrations
+// Never reorder or remove a migration, only append new ones
+var migrations = []migration{
+	{name: "initial-version", apply: func(tx *buntdb.Tx) error { return nil }},
+	{name: "reset-legacy-storage", apply: func(tx *buntdb.Tx) error { return tx.DeleteAll() }},
+	{name: "reset-parses", apply: deleteKeysMigration("wclogs-parses:*")},
+	{name: "reset-latest-reports", apply: deleteKeysMigration("wclogs-latest-report:*")},
+	// Difficulty was not stored yet, legacy parses are resolved against zones once a connected instance checks them
+	{name: "partition-parses", apply: migratePartitionParses},
+}
+
+// currentDatabaseVersion is the database version once every migration is applied
+var currentDatabaseVersion = len(migrations)
+
+// fetchDatabaseVersion reads database version, a missing version is considered as 0
+func fetchDatabaseVersion(db *buntdb.DB) (int, error) {
+	dbVersion := 0
+	err := db.View(func(tx *buntdb.Tx) error {
+		val, err := tx.Get("version")
+		if err == buntdb.ErrNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		dbVersion, err = strconv.Atoi(val)
+		return err
+	})
+
+	return dbVersion, err
+}
+
+// upgradeDatabaseIfNecessary checks database version and applies missing migrations,
+// a snapshot of the database is saved next to dbPath beforehand
+func upgradeDatabaseIfNecessary(db *buntdb.DB, dbPath string) error {
+	dbVersion, err := fetchDatabaseVersion(db)
+	if err != nil {
+		return err
+	}
+
+	if dbVersion > currentDatabaseVersion {
+		return fmt.Errorf("database version %d is newer than supported version %d", dbVersion, currentDatabaseVersion)
+	}
+	if dbVersion == currentDatabaseVersion {
+		return nil
+	}
+
+	snapshotPath := fmt.Sprintf("%s.v%d-%s.bak", dbPath, dbVersion, time.Now().Format("20060102-150405"))
+	if err = snapshotDatabase(db, snapshotPath); err != nil {
+		return fmt.Errorf("failed to snapshot database before migrating: %w", err)
+	}
+	log.Info().Str("path", snapshotPath).Int("version", dbVersion).Msg("Database snapshot saved before migrating")
+
+	return applyMigrations(db, dbVersion)
+}
+
+// applyMigrations applies each migration after dbVersion, version is updated in the same transaction as each migration
+func applyMigrations(db *buntdb.DB, dbVersion int) error {
+	for version := dbVersion; version < len(migrations); version++ {
+		m := migrations[version]
+		log.Info().Int("version", version+1).Str("migration", m.name).Msg("Applying database migration")
+
+		err := db.Update(func(tx *buntdb.Tx) error {
+			if err := m.apply(tx); err != nil {
+				return err
+			}
+
+			_, _, err := tx.Set("version", strconv.Itoa(version+1), nil)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("migration %d %s failed: %w", version+1, m.name, err)
+		}
+	}
+
+	return nil
+}
+
+// deleteKeysMigration returns a migration deleting every key matching pattern
+func deleteKeysMigration(pattern string) func(tx *buntdb.Tx) error {
+	return func(tx *buntdb.Tx) error {
+		var keysToDelete []string
+		err := tx.AscendKeys(pattern, func(key, value string) bool {
+			keysToDelete = append(keysToDelete, key)
+			return true
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, key := range keysToDelete {
+			if _, err = tx.Delete(key); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
+
+// migratePartitionParses moves parses under the partition they were ranked in
+func migratePartitionParses(tx *buntdb.Tx) error {
+	legacyParses := make(map[string]string)
+	err := tx.AscendKeys("wclogs-parses:*", func(key, value string) bool {
+		legacyParses[key] = value
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	for key, value := range legacyParses {
+		var legacy map[wclogs.ZoneID]wclogs.SizeRankings
+		if err = json.Unmarshal([]byte(value), &legacy); err != nil {
+			return err
+		}
+
+		// Difficulty was not stored yet
+		parses := make(wclogs.Parses)
+		for zoneID, sizeRankings := range legacy {
+			for size, metricRankings := range sizeRankings {
+				parses.MergeMetricRankings(zoneID, wclogs.UnknownDifficulty, size, &metricRankings)
+			}
+		}
+
+		bytes, err := json.Marshal(parses)
+		if err != nil {
+			return err
+		}
+		if _, _, err = tx.Set(key, string(bytes), nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/tidwall/buntdb"
+	"github.com/zergrael/epa/wclogs"
+)
+
+// legacyParsesFixture is a version 4 parses value, rankings per zone and size without partition nor difficulty
+const legacyParsesFixture = `{"1017":{"25":{"dps":{"Partition":2,"Rankings":[{"Encounter":{"ID":101,"Name":"Lord Marrowgar"},"RankPercent":87.5}]}},` +
+	`"10":{"hps":{"Partition":1,"Rankings":[{"Encounter":{"ID":102,"Name":"Lady Deathwhisper"},"RankPercent":42}]}}}}`
+
+// partitionParsesFixture is legacyParsesFixture once moved under partitions, with an unknown difficulty
+func partitionParsesFixture(t *testing.T) string {
+	var legacy map[wclogs.ZoneID]wclogs.SizeRankings
+	if err := json.Unmarshal([]byte(legacyParsesFixture), &legacy); err != nil {
+		t.Fatal(err)
+	}
+
+	parses := make(wclogs.Parses)
+	for zoneID, sizeRankings := range legacy {
+		for size, metricRankings := range sizeRankings {
+			parses.MergeMetricRankings(zoneID, wclogs.UnknownDifficulty, size, &metricRankings)
+		}
+	}
+
+	return mustMarshal(t, parses)
+}
+
+func mustMarshal(t *testing.T, v interface{}) string {
+	t.Helper()
+	bytes, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(bytes)
+}
+
+// openFixtureDatabase opens an in-memory database containing fixture keys
+func openFixtureDatabase(t *testing.T, fixture map[string]string) *buntdb.DB {
+	t.Helper()
+	fixtureDB, err := buntdb.Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = fixtureDB.Close() })
+
+	err = fixtureDB.Update(func(tx *buntdb.Tx) error {
+		for key, value := range fixture {
+			if _, _, err := tx.Set(key, value, nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return fixtureDB
+}
+
+// databaseContent returns every key and value of a database
+func databaseContent(t *testing.T, db *buntdb.DB) map[string]string {
+	t.Helper()
+	content := make(map[string]string)
+	err := db.View(func(tx *buntdb.Tx) error {
+		return tx.Ascend("", func(key, value string) bool {
+			content[key] = value
+			return true
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return content
+}
+
+// withMigrations replaces the migrations list for the duration of a test
+func withMigrations(t *testing.T, list []migration) {
+	previous := migrations
+	migrations = list
+	t.Cleanup(func() { migrations = previous })
+}
+
+func TestApplyMigrations(t *testing.T) {
+	settings := `{"ManagerRoleID":"42"}`
+	roster := `[{"ID":1,"Name":"Foo","Server":"Bar","Region":"EU","ChannelID":"7"}]`
+	latestReport := `{"Code":"abc","EndTime":"2023-01-01T00:00:00Z"}`
+	creds := `{"client_id":"id","client_secret":"secret"}`
+
+	tests := []struct {
+		name    string
+		fixture map[string]string
+		want    map[string]string
+	}{
+		{
+			name:    "version 0 resets legacy storage",
+			fixture: map[string]string{"wclogs-parses:1": legacyParsesFixture, "legacy": "value"},
+			want:    map[string]string{},
+		},
+		{
+			name:    "version 1 resets legacy storage",
+			fixture: map[string]string{"version": "1", "wclogs-parses:1": legacyParsesFixture, "legacy": "value"},
+			want:    map[string]string{},
+		},
+		{
+			// The legacy upgrade switch skipped latest reports reset from version 2
+			name: "version 2 resets parses and latest reports",
+			fixture: map[string]string{"version": "2", "guild-settings:1": settings, "wclogs-creds:1": creds,
+				"wclogs-parses:1": legacyParsesFixture, "wclogs-latest-report:1": latestReport},
+			want: map[string]string{"guild-settings:1": settings, "wclogs-creds:1": creds},
+		},
+		{
+			name: "version 3 resets latest reports",
+			fixture: map[string]string{"version": "3", "wclogs-tracked-characters:1": roster,
+				"wclogs-latest-report:1": latestReport},
+			want: map[string]string{"wclogs-tracked-characters:1": roster},
+		},
+		{
+			name:    "version 4 moves parses under partitions",
+			fixture: map[string]string{"version": "4", "wclogs-parses:1": legacyParsesFixture, "wclogs-latest-report:1": latestReport},
+			want:    map[string]string{"wclogs-parses:1": partitionParsesFixture(t), "wclogs-latest-report:1": latestReport},
+		},
+		{
+			name:    "current version is unchanged",
+			fixture: map[string]string{"version": strconv.Itoa(currentDatabaseVersion), "wclogs-tracked-characters:1": roster},
+			want:    map[string]string{"wclogs-tracked-characters:1": roster},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fixtureDB := openFixtureDatabase(t, tt.fixture)
+
+			dbVersion, err := fetchDatabaseVersion(fixtureDB)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = applyMigrations(fixtureDB, dbVersion); err != nil {
+				t.Fatalf("applyMigrations failed: %v", err)
+			}
+
+			version, err := fetchDatabaseVersion(fixtureDB)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if version != len(migrations) {
+				t.Errorf("version = %d, want %d", version, len(migrations))
+			}
+
+			got := databaseContent(t, fixtureDB)
+			delete(got, "version")
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("content = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyMigrationsRollback(t *testing.T) {
+	failing := migration{name: "failing", apply: func(tx *buntdb.Tx) error {
+		if _, _, err := tx.Set("wclogs-parses:1", "changed", nil); err != nil {
+			return err
+		}
+		return errors.New("failing migration")
+	}}
+	withMigrations(t, append(append([]migration{}, migrations...), failing))
+
+	fixture := map[string]string{"version": strconv.Itoa(currentDatabaseVersion), "wclogs-parses:1": "{}"}
+	fixtureDB := openFixtureDatabase(t, fixture)
+
+	err := applyMigrations(fixtureDB, currentDatabaseVersion)
+	if err == nil {
+		t.Fatal("applyMigrations succeeded, want failing migration error")
+	}
+
+	if got := databaseContent(t, fixtureDB); !reflect.DeepEqual(got, fixture) {
+		t.Errorf("content = %v, want unchanged %v", got, fixture)
+	}
+}
+
+func TestUpgradeDatabaseNewerVersion(t *testing.T) {
+	fixtureDB := openFixtureDatabase(t, map[string]string{"version": strconv.Itoa(currentDatabaseVersion + 1)})
+	if err := upgradeDatabaseIfNecessary(fixtureDB, t.TempDir()+"/data.db"); err == nil {
+		t.Error("upgradeDatabaseIfNecessary succeeded on a newer database version")
+	}
+}
@@ -0,0 +1,346 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/zergrael/epa/wclogs"
+)
+
+// EventType identifies a tracking event, also used as announcement metrics label
+type EventType string
+
+const (
+	EventNewReport    EventType = "new-report"
+	EventNewPartition EventType = "new-partition"
+	EventNewParse     EventType = "new-parse"
+	EventFirstKill    EventType = "first-kill"
+)
+
+// Event is a tracking event sent to every notifier of a guild
+type Event interface {
+	Type() EventType
+	Guild() string
+}
+
+// NewReportEvent is sent when tracked characters appear in a new report
+type NewReportEvent struct {
+	GuildID    string              `json:"guild_id"`
+	ChannelID  string              `json:"channel_id"`
+	ReportCode string              `json:"report_code"`
+	EndTime    time.Time           `json:"end_time"`
+	Characters []*TrackedCharacter `json:"characters"`
+}
+
+func (e *NewReportEvent) Type() EventType { return EventNewReport }
+func (e *NewReportEvent) Guild() string   { return e.GuildID }
+
+// NewPartitionEvent is sent when tracked characters parses are compared against a new rankings partition
+type NewPartitionEvent struct {
+	GuildID    string              `json:"guild_id"`
+	ChannelID  string              `json:"channel_id"`
+	ZoneID     wclogs.ZoneID       `json:"zone_id"`
+	ZoneName   string              `json:"zone_name"`
+	Partition  wclogs.Partition    `json:"partition"`
+	Characters []*TrackedCharacter `json:"characters"`
+}
+
+func (e *NewPartitionEvent) Type() EventType { return EventNewPartition }
+func (e *NewPartitionEvent) Guild() string   { return e.GuildID }
+
+// ParseEvent details the ranking of a tracked character on an encounter killed in a report
+type ParseEvent struct {
+	GuildID        string            `json:"guild_id"`
+	ChannelID      string            `json:"channel_id"`
+	Character      *TrackedCharacter `json:"character"`
+	ReportCode     string            `json:"report_code"`
+	FightID        int               `json:"fight_id,omitempty"`
+	ZoneID         wclogs.ZoneID     `json:"zone_id"`
+	ZoneName       string            `json:"zone_name"`
+	Difficulty     wclogs.Difficulty `json:"difficulty"`
+	DifficultyName string            `json:"difficulty_name"`
+	Size           wclogs.RaidSize   `json:"size"`
+	Metric         wclogs.Metric     `json:"metric"`
+	EncounterID    int               `json:"encounter_id"`
+	EncounterName  string            `json:"encounter_name"`
+	RankPercent    float64           `json:"rank_percent"`
+}
+
+func (e *ParseEvent) Guild() string { return e.GuildID }
+
+// NewParseEvent is sent when a tracked character improves a parse
+type NewParseEvent struct {
+	ParseEvent
+	PreviousRankPercent float64 `json:"previous_rank_percent"`
+}
+
+func (e *NewParseEvent) Type() EventType { return EventNewParse }
+
+// FirstKillEvent is sent when a tracked character gets its first ranked kill of an encounter
+type FirstKillEvent struct {
+	ParseEvent
+}
+
+func (e *FirstKillEvent) Type() EventType { return EventFirstKill }
+
+// newParseEvent describes the ranking of a tracked character in a report raid
+func newParseEvent(guildID string, report *wclogs.Report, raid wclogs.ReportRaid, zone *wclogs.Zone, metric wclogs.Metric, ranking *wclogs.Ranking, char *TrackedCharacter) ParseEvent {
+	event := ParseEvent{
+		GuildID:        guildID,
+		ChannelID:      char.ChannelID,
+		Character:      char,
+		ReportCode:     report.Code,
+		ZoneID:         raid.ZoneID,
+		Difficulty:     raid.Difficulty,
+		DifficultyName: zone.DifficultyName(raid.Difficulty),
+		Size:           raid.Size,
+		Metric:         metric,
+		EncounterID:    ranking.Encounter.ID,
+		EncounterName:  ranking.Encounter.Name,
+		RankPercent:    ranking.RankPercent,
+	}
+	if zone != nil {
+		event.ZoneName = zone.Name
+	}
+	if fight := report.LastFightForEncounter(raid, ranking.Encounter.ID); fight != nil {
+		event.FightID = fight.ID
+	}
+
+	return event
+}
+
+// Notifier sends tracking events to an outgoing sink
+type Notifier interface {
+	Name() string
+	Notify(event Event) error
+}
+
+// commandNotifiers replace every guild notifiers while running a one-shot command
+var commandNotifiers []Notifier
+
+// guildNotifiers returns every notifier of a guild, Discord announcements first
+func guildNotifiers(guildID string) []Notifier {
+	if commandNotifiers != nil {
+		return commandNotifiers
+	}
+
+	notifiers := []Notifier{discordNotifier{}}
+
+	settings, err := fetchGuildSettings(db, guildID)
+	if err != nil {
+		log.Error().Err(err).Str("guildID", guildID).Msg("fetchGuildSettings failed")
+		return notifiers
+	}
+
+	for _, url := range settings.NotificationWebhooks {
+		notifiers = append(notifiers, newHTTPNotifier(url))
+	}
+
+	return notifiers
+}
+
+// notify sends an event to every notifier of its guild, failures do not prevent other notifiers
+func notify(event Event) {
+	for _, notifier := range guildNotifiers(event.Guild()) {
+		if err := notifier.Notify(event); err != nil {
+			log.Error().Err(err).Str("guildID", event.Guild()).Str("notifier", notifier.Name()).
+				Str("event", string(event.Type())).Msg("Failed to notify event")
+		}
+	}
+}
+
+// discordNotifier announces events in the Discord channel of tracked characters
+type discordNotifier struct{}
+
+func (n discordNotifier) Name() string {
+	return "discord"
+}
+
+// Notify formats and sends an announcement in the guild language
+func (n discordNotifier) Notify(event Event) error {
+	lang := guildLang(event.Guild())
+	switch e := event.(type) {
+	case *NewReportEvent:
+		return announceNewReport(e, lang)
+	case *NewPartitionEvent:
+		return announceNewPartition(e, lang)
+	case *NewParseEvent:
+		return announceParse(e, lang)
+	case *FirstKillEvent:
+		return announceFirstKill(e, lang)
+	}
+
+	return fmt.Errorf("unsupported event %s", event.Type())
+}
+
+// httpNotifierTimeout bounds the delivery of an event to an HTTP webhook
+const httpNotifierTimeout = 10 * time.Second
+
+// httpNotifier posts events as JSON to a generic HTTP webhook
+type httpNotifier struct {
+	url    string
+	client *http.Client
+}
+
+// httpNotification is the JSON body posted to HTTP webhooks
+type httpNotification struct {
+	Type  EventType `json:"type"`
+	Time  time.Time `json:"time"`
+	Event Event     `json:"event"`
+}
+
+// errInsecureWebhook is returned for webhooks stored before https was required, or redirecting to http
+var errInsecureWebhook = errors.New("webhook URL is not https")
+
+// errNonPublicAddress is returned when a webhook resolves to an address of the bot host or its networks
+var errNonPublicAddress = errors.New("webhook address is not public")
+
+// publicAddressDialer only connects to public addresses, checked once the host name is resolved
+var publicAddressDialer = &net.Dialer{Timeout: httpNotifierTimeout, Control: rejectNonPublicAddress}
+
+// httpNotifierClient is shared by every HTTP webhook, so that idle connections are reused and eventually closed
+var httpNotifierClient = &http.Client{
+	Timeout: httpNotifierTimeout,
+	// No proxy, it would connect on our behalf without the address check
+	Transport: &http.Transport{
+		DialContext:         publicAddressDialer.DialContext,
+		TLSHandshakeTimeout: httpNotifierTimeout,
+		MaxIdleConnsPerHost: 2,
+		IdleConnTimeout:     time.Minute,
+	},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if req.URL.Scheme != "https" {
+			return errInsecureWebhook
+		}
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return nil
+	},
+}
+
+func newHTTPNotifier(url string) *httpNotifier {
+	return &httpNotifier{url: url, client: httpNotifierClient}
+}
+
+// rejectNonPublicAddress is a dialer Control refusing connections to non public addresses
+func rejectNonPublicAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+		return fmt.Errorf("%w: %s", errNonPublicAddress, host)
+	}
+
+	return nil
+}
+
+// isPublicIP reports whether an IP is neither loopback, private, link-local, multicast nor unspecified
+func isPublicIP(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil && ip4[0] == 0 {
+		return false
+	}
+
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsUnspecified() &&
+		!ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() && !ip.IsMulticast()
+}
+
+func (n *httpNotifier) Name() string {
+	return "http"
+}
+
+// Notify posts the event in background, so that slow webhooks never hold the guild ticker
+// Any response status but 2xx is logged as a failure
+func (n *httpNotifier) Notify(event Event) error {
+	body, err := json.Marshal(&httpNotification{Type: event.Type(), Time: time.Now(), Event: event})
+	if err != nil {
+		return err
+	}
+
+	if !startInFlight() {
+		return errShuttingDown
+	}
+	go func() {
+		defer doneInFlight()
+		err := n.post(body)
+		observeAnnouncement("http-"+string(event.Type()), err)
+		if err != nil {
+			log.Error().Err(err).Str("guildID", event.Guild()).Str("notifier", n.Name()).
+				Str("event", string(event.Type())).Msg("Failed to notify event")
+		}
+	}()
+
+	return nil
+}
+
+// post sends a JSON body to the webhook URL
+func (n *httpNotifier) post(body []byte) error {
+	if !strings.HasPrefix(n.url, "https://") {
+		return errInsecureWebhook
+	}
+
+	resp, err := n.client.Post(n.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %s", resp.Status)
+	}
+
+	return nil
+}
+
+// consoleNotifier prints events as text lines, used to preview announcements
+type consoleNotifier struct {
+	out io.Writer
+}
+
+func (n consoleNotifier) Name() string {
+	return "console"
+}
+
+// Notify prints a single line summary of the event
+func (n consoleNotifier) Notify(event Event) error {
+	var line string
+	switch e := event.(type) {
+	case *NewReportEvent:
+		line = fmt.Sprintf("Report %s%s ended %s : %s", reportsURL, e.ReportCode,
+			e.EndTime.Format(time.RFC3339), charactersSlugs(e.Characters))
+	case *NewPartitionEvent:
+		line = fmt.Sprintf("%s partition %d : %s", e.ZoneName, e.Partition, charactersSlugs(e.Characters))
+	case *NewParseEvent:
+		line = fmt.Sprintf("%s %s (%d %s) %s : %.2f -> %.2f", e.Character.Slug(), e.EncounterName,
+			e.Size, e.DifficultyName, e.Metric, e.PreviousRankPercent, e.RankPercent)
+	case *FirstKillEvent:
+		line = fmt.Sprintf("%s %s (%d %s) %s : %.2f", e.Character.Slug(), e.EncounterName,
+			e.Size, e.DifficultyName, e.Metric, e.RankPercent)
+	default:
+		return fmt.Errorf("unsupported event %s", event.Type())
+	}
+
+	_, err := fmt.Fprintf(n.out, "[%s] %s\n", event.Type(), line)
+	return err
+}
+
+// charactersSlugs returns a comma separated list of characters slugs
+func charactersSlugs(chars []*TrackedCharacter) string {
+	var slugs []string
+	for _, c := range chars {
+		slugs = append(slugs, c.Slug())
+	}
+
+	return strings.Join(slugs, ", ")
+}
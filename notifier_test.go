@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIsPublicIP(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{ip: "1.1.1.1", want: true},
+		{ip: "2606:4700:4700::1111", want: true},
+		{ip: "127.0.0.1"},
+		{ip: "::1"},
+		{ip: "10.1.2.3"},
+		{ip: "172.16.0.1"},
+		{ip: "192.168.1.1"},
+		{ip: "169.254.169.254"},
+		{ip: "fe80::1"},
+		{ip: "fd00::1"},
+		{ip: "0.0.0.0"},
+		{ip: "::"},
+		{ip: "::ffff:127.0.0.1"},
+		{ip: "224.0.0.1"},
+	}
+
+	for _, tt := range tests {
+		if got := isPublicIP(net.ParseIP(tt.ip)); got != tt.want {
+			t.Errorf("isPublicIP(%s) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestHTTPNotifierRejectsNonPublicAddress(t *testing.T) {
+	requests := 0
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer server.Close()
+
+	if err := newHTTPNotifier(server.URL).post([]byte("{}")); !errors.Is(err, errNonPublicAddress) {
+		t.Errorf("post error = %v, want %v", err, errNonPublicAddress)
+	}
+	if err := newHTTPNotifier("http://example.com/").post([]byte("{}")); !errors.Is(err, errInsecureWebhook) {
+		t.Errorf("post error = %v, want %v", err, errInsecureWebhook)
+	}
+	if requests != 0 {
+		t.Errorf("server received %d requests, want none", requests)
+	}
+}
+
+func TestAddNotificationWebhookInvalidURL(t *testing.T) {
+	invalidURL := tr(LangEnglish, "notification.invalid-url")
+	for _, webhookURL := range []string{
+		"not a URL",
+		"http://example.com/events",
+		"https:///events",
+		"https://127.0.0.1:8080/events",
+		"https://[::1]/events",
+		"https://169.254.169.254/latest/meta-data",
+		"https://192.168.1.1/events",
+	} {
+		if got := addNotificationWebhook("1", webhookURL, LangEnglish); got != invalidURL {
+			t.Errorf("addNotificationWebhook(%q) = %q, want %q", webhookURL, got, invalidURL)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/rs/zerolog/log"
+)
+
+// GuildSettings contains per-guild bot settings
+type GuildSettings struct {
+	// ManagerRoleID restricts roster mutations to members with this role, everyone is allowed if empty
+	ManagerRoleID string `json:",omitempty"`
+	// Language is used for announcements and members with an unsupported client locale, guild locale if empty
+	Language Lang `json:",omitempty"`
+	// WebhookAnnouncements sends announcements through a channel webhook, under the character name
+	WebhookAnnouncements bool `json:",omitempty"`
+	// NotificationWebhooks are HTTP URLs receiving every tracking event as JSON, in addition to Discord announcements
+	NotificationWebhooks []string `json:",omitempty"`
+	// CredentialsRejected is set once admins were asked to register new WarcraftLogs credentials
+	CredentialsRejected bool `json:",omitempty"`
+}
+
+// isManager returns true if member can change a guild tracked characters, with the guild manager role ID
+// Members allowed to manage the guild are always managers
+func isManager(guildID string, member *discordgo.Member) (bool, string) {
+	if member == nil {
+		return false, ""
+	}
+
+	if member.Permissions&(discordgo.PermissionAdministrator|discordgo.PermissionManageServer) != 0 {
+		return true, ""
+	}
+
+	settings, err := fetchGuildSettings(db, guildID)
+	if err != nil {
+		log.Error().Err(err).Str("guildID", guildID).Msg("fetchGuildSettings failed")
+		return false, ""
+	}
+
+	if settings.ManagerRoleID == "" {
+		return true, ""
+	}
+
+	return arrayContains(member.Roles, settings.ManagerRoleID), settings.ManagerRoleID
+}
+
+// setManagerRole stores the guild EPA manager role, an empty roleID allows everyone
+func setManagerRole(guildID, roleID string, lang Lang) string {
+	log.Debug().Str("guildID", guildID).Str("roleID", roleID).Msg("setManagerRole")
+	settings, err := fetchGuildSettings(db, guildID)
+	if err != nil {
+		log.Error().Err(err).Str("guildID", guildID).Msg("fetchGuildSettings failed")
+		return tr(lang, "settings.read-failed")
+	}
+
+	settings.ManagerRoleID = roleID
+	err = storeGuildSettings(db, guildID, settings)
+	if err != nil {
+		log.Error().Err(err).Str("guildID", guildID).Msg("storeGuildSettings failed")
+		return tr(lang, "settings.store-failed")
+	}
+
+	if roleID == "" {
+		return tr(lang, "manager.everyone")
+	}
+
+	return tr(lang, "manager.role", roleID)
+}
+
+// setLanguage stores the guild default language
+func setLanguage(guildID string, lang Lang) string {
+	log.Debug().Str("guildID", guildID).Str("lang", string(lang)).Msg("setLanguage")
+	settings, err := fetchGuildSettings(db, guildID)
+	if err != nil {
+		log.Error().Err(err).Str("guildID", guildID).Msg("fetchGuildSettings failed")
+		return tr(lang, "settings.read-failed")
+	}
+
+	settings.Language = lang
+	err = storeGuildSettings(db, guildID, settings)
+	if err != nil {
+		log.Error().Err(err).Str("guildID", guildID).Msg("storeGuildSettings failed")
+		return tr(lang, "settings.store-failed")
+	}
+
+	return tr(lang, "language.set")
+}
+
+// setWebhookAnnouncements enables or disables announcements through channel webhooks
+func setWebhookAnnouncements(guildID string, enabled bool, lang Lang) string {
+	log.Debug().Str("guildID", guildID).Bool("enabled", enabled).Msg("setWebhookAnnouncements")
+	settings, err := fetchGuildSettings(db, guildID)
+	if err != nil {
+		log.Error().Err(err).Str("guildID", guildID).Msg("fetchGuildSettings failed")
+		return tr(lang, "settings.read-failed")
+	}
+
+	settings.WebhookAnnouncements = enabled
+	err = storeGuildSettings(db, guildID, settings)
+	if err != nil {
+		log.Error().Err(err).Str("guildID", guildID).Msg("storeGuildSettings failed")
+		return tr(lang, "settings.store-failed")
+	}
+
+	if enabled {
+		return tr(lang, "webhook.enabled")
+	}
+
+	return tr(lang, "webhook.disabled")
+}
+
+// webhookAnnouncementsEnabled returns true if a guild announcements are sent through channel webhooks
+func webhookAnnouncementsEnabled(guildID string) bool {
+	settings, err := fetchGuildSettings(db, guildID)
+	if err != nil {
+		log.Error().Err(err).Str("guildID", guildID).Msg("fetchGuildSettings failed")
+		return false
+	}
+
+	return settings.WebhookAnnouncements
+}
+
+// setCredentialsRejected stores whether a guild WarcraftLogs credentials are rejected, returns true if it changed
+func setCredentialsRejected(guildID string, rejected bool) bool {
+	settings, err := fetchGuildSettings(db, guildID)
+	if err != nil {
+		log.Error().Err(err).Str("guildID", guildID).Msg("fetchGuildSettings failed")
+		return false
+	}
+
+	if settings.CredentialsRejected == rejected {
+		return false
+	}
+
+	settings.CredentialsRejected = rejected
+	err = storeGuildSettings(db, guildID, settings)
+	if err != nil {
+		log.Error().Err(err).Str("guildID", guildID).Msg("storeGuildSettings failed")
+		return false
+	}
+
+	return true
+}
+
+// credentialsRejected returns true if a guild WarcraftLogs credentials were rejected and not registered again
+func credentialsRejected(guildID string) bool {
+	settings, err := fetchGuildSettings(db, guildID)
+	if err != nil {
+		log.Error().Err(err).Str("guildID", guildID).Msg("fetchGuildSettings failed")
+		return false
+	}
+
+	return settings.CredentialsRejected
+}
+
+// addNotificationWebhook adds an HTTP URL receiving the guild tracking events
+func addNotificationWebhook(guildID, webhookURL string, lang Lang) string {
+	log.Debug().Str("guildID", guildID).Msg("addNotificationWebhook")
+	u, err := url.Parse(webhookURL)
+	if err != nil || u.Scheme != "https" || u.Hostname() == "" {
+		return tr(lang, "notification.invalid-url")
+	}
+	// Host names are checked when connecting, once resolved
+	if ip := net.ParseIP(u.Hostname()); ip != nil && !isPublicIP(ip) {
+		return tr(lang, "notification.invalid-url")
+	}
+
+	settings, err := fetchGuildSettings(db, guildID)
+	if err != nil {
+		log.Error().Err(err).Str("guildID", guildID).Msg("fetchGuildSettings failed")
+		return tr(lang, "settings.read-failed")
+	}
+
+	if arrayContains(settings.NotificationWebhooks, webhookURL) {
+		return tr(lang, "notification.exists")
+	}
+
+	settings.NotificationWebhooks = append(settings.NotificationWebhooks, webhookURL)
+	err = storeGuildSettings(db, guildID, settings)
+	if err != nil {
+		log.Error().Err(err).Str("guildID", guildID).Msg("storeGuildSettings failed")
+		return tr(lang, "settings.store-failed")
+	}
+
+	return tr(lang, "notification.added")
+}
+
+// removeNotificationWebhook stops sending the guild tracking events to an HTTP URL
+func removeNotificationWebhook(guildID, webhookURL string, lang Lang) string {
+	log.Debug().Str("guildID", guildID).Msg("removeNotificationWebhook")
+	settings, err := fetchGuildSettings(db, guildID)
+	if err != nil {
+		log.Error().Err(err).Str("guildID", guildID).Msg("fetchGuildSettings failed")
+		return tr(lang, "settings.read-failed")
+	}
+
+	var webhooks []string
+	for _, w := range settings.NotificationWebhooks {
+		if w != webhookURL {
+			webhooks = append(webhooks, w)
+		}
+	}
+	if len(webhooks) == len(settings.NotificationWebhooks) {
+		return tr(lang, "notification.unknown")
+	}
+
+	settings.NotificationWebhooks = webhooks
+	err = storeGuildSettings(db, guildID, settings)
+	if err != nil {
+		log.Error().Err(err).Str("guildID", guildID).Msg("storeGuildSettings failed")
+		return tr(lang, "settings.store-failed")
+	}
+
+	return tr(lang, "notification.removed")
+}
+
+// listNotificationWebhooks lists the HTTP URLs receiving the guild tracking events
+func listNotificationWebhooks(guildID string, lang Lang) string {
+	settings, err := fetchGuildSettings(db, guildID)
+	if err != nil {
+		log.Error().Err(err).Str("guildID", guildID).Msg("fetchGuildSettings failed")
+		return tr(lang, "settings.read-failed")
+	}
+
+	if len(settings.NotificationWebhooks) == 0 {
+		return tr(lang, "notification.none")
+	}
+
+	return tr(lang, "notification.list") + "\n" + strings.Join(settings.NotificationWebhooks, "\n")
+}
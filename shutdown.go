@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// botCtx is cancelled on shutdown, every guild ticker context derives from it
+var botCtx, stopBot = context.WithCancel(context.Background())
+
+// requestsCtx is cancelled once in-flight work is drained on shutdown, every WarcraftLogs requests context derives from it
+var requestsCtx, cancelRequests = context.WithCancel(context.Background())
+
+// errShuttingDown is returned for work refused once shutdown started
+var errShuttingDown = errors.New("shutting down")
+
+// inFlight tracks running checks and announcements, waited for on shutdown
+var inFlight struct {
+	sync.Mutex
+	wg     sync.WaitGroup
+	closed bool
+}
+
+// startInFlight registers a unit of work, it returns false once shutdown started and the work should be skipped
+func startInFlight() bool {
+	inFlight.Lock()
+	defer inFlight.Unlock()
+	if inFlight.closed {
+		return false
+	}
+
+	inFlight.wg.Add(1)
+	return true
+}
+
+// doneInFlight marks a unit of work registered with startInFlight as done
+func doneInFlight() {
+	inFlight.wg.Done()
+}
+
+// drainInFlight refuses new work and waits for in-flight work up to timeout, returns false on timeout
+func drainInFlight(timeout time.Duration) bool {
+	inFlight.Lock()
+	inFlight.closed = true
+	inFlight.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		inFlight.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
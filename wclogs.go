@@ -1,10 +1,14 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/bwmarrin/discordgo"
 	"math/rand"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/rs/zerolog/log"
@@ -12,17 +16,40 @@ import (
 )
 
 var trackedCharacters map[string][]*TrackedCharacter
+
+// rosterMutex guards trackedCharacters, mutations also keep memory and database in sync
+var rosterMutex sync.Mutex
+
+// errWCLogsMissing is returned when a guild WCLogs instance was destroyed, or never registered
+var errWCLogsMissing = errors.New("no WCLogs instance for guild")
+
+// guildsMutex guards logs, tickers and WCLogs contexts, read by interaction handlers while a ticker may destroy them
+var guildsMutex sync.RWMutex
+
 var characterTrackTicker map[string]*time.Ticker
-var timerStopper map[string]chan bool
 
-// TODO: Adjust ticker duration based on latest report age
-const characterTrackTickerDuration = 1 * time.Minute
+// tickerCancel stops each guild ticker goroutine
+var tickerCancel map[string]context.CancelFunc
+
+// wclogsCtx is each guild WCLogs instance context, cancelled with in-flight requests when the instance is destroyed
+var wclogsCtx map[string]context.Context
+
+// wclogsCancel cancels each guild wclogsCtx
+var wclogsCancel map[string]context.CancelFunc
 
 type TrackedCharacter struct {
 	*wclogs.Character
 	ChannelID string
 }
 
+// withRoles returns a copy of a tracked character with other roles, tracked characters are shared and never modified
+func (t *TrackedCharacter) withRoles(roles []wclogs.Role) *TrackedCharacter {
+	char := *t.Character
+	char.Roles = roles
+
+	return &TrackedCharacter{Character: &char, ChannelID: t.ChannelID}
+}
+
 var goodParse = []string{
 	":partying_face:",
 	":muscle:",
@@ -32,14 +59,25 @@ var goodParse = []string{
 	":star_struck:",
 	":crown:",
 }
-var badParse = []string{
-	"Nice try, but you suck",
-	"That was nice. Maybe you should try harder ?",
-	":pleading_face:",
-	"At some point, you might have more than a green parse",
-	"This is bad, but it could be worse",
-	"Better luck next time",
-	"Here, have a participation trophy :trophy:",
+var badParse = map[Lang][]string{
+	LangEnglish: {
+		"Nice try, but you suck",
+		"That was nice. Maybe you should try harder ?",
+		":pleading_face:",
+		"At some point, you might have more than a green parse",
+		"This is bad, but it could be worse",
+		"Better luck next time",
+		"Here, have a participation trophy :trophy:",
+	},
+	LangFrench: {
+		"Bel essai, mais c'est nul",
+		"C'était sympa. Peut-être qu'il faudrait forcer un peu ?",
+		":pleading_face:",
+		"Un jour, peut-être, un parse plus que vert",
+		"C'est mauvais, mais ça pourrait être pire",
+		"Plus de chance la prochaine fois",
+		"Tiens, un trophée de participation :trophy:",
+	},
 }
 
 // instantiateWCLogsForGuild tries to fetch wclogs.Credentials from database and validate them before starting ticker
@@ -58,189 +96,474 @@ func instantiateWCLogsForGuild(guildID string) {
 		return
 	}
 
-	w := wclogs.New(creds, wclogs.Classic, nil)
-	if !w.Connect() {
-		log.Warn().Str("guildID", guildID).Msg("Failed to reuse credentials for guild")
+	w := newWCLogs(creds)
+	err = w.Connect(requestsCtx)
+	if errors.Is(err, wclogs.ErrUnauthorized) {
+		disableWCLogsForGuild(guildID, err)
+		return
+	}
+	if err != nil {
+		// Probably a WarcraftLogs outage, following ticks will retry
+		log.Warn().Err(err).Str("guildID", guildID).Msg("Failed to reuse credentials for guild")
+	} else {
+		setCredentialsRejected(guildID, false)
 	}
 
 	log.Info().Str("guildID", guildID).Msg("WCLogs instance successful")
-	logs[guildID] = w
+	setGuildWCLogs(guildID, w)
+	loadTrackedCharacters(guildID)
+
+	// Setup tracking timer
+	setupWCLogsTicker(guildID)
+}
 
+// loadTrackedCharacters reads a guild tracked characters from database, none if missing
+func loadTrackedCharacters(guildID string) {
+	characters, err := fetchWCLogsTrackedCharacters(db, guildID)
+	if err != nil {
+		log.Warn().Err(err).Msg("No currently tracked characters")
+		characters = make([]*TrackedCharacter, 0)
+	}
+
+	rosterMutex.Lock()
+	defer rosterMutex.Unlock()
 	if trackedCharacters == nil {
 		trackedCharacters = make(map[string][]*TrackedCharacter)
 	}
-	trackedCharacters[guildID], err = fetchWCLogsTrackedCharacters(db, guildID)
+	trackedCharacters[guildID] = characters
+}
+
+// disableWCLogsForGuild stops tracking for a guild whose credentials were rejected, and asks admins to register new ones
+// Admins are only notified once until credentials are accepted again
+func disableWCLogsForGuild(guildID string, cause error) {
+	log.Warn().Err(cause).Str("guildID", guildID).Msg("WCLogs credentials rejected, disabling tracking for guild")
+	if guildWCLogs(guildID) != nil {
+		destroyWCLogsForGuild(guildID)
+	}
+
+	if !setCredentialsRejected(guildID, true) {
+		return
+	}
+
+	channelIDs := adminChannelIDs(guildID)
+	if len(channelIDs) == 0 {
+		log.Warn().Str("guildID", guildID).Msg("No channel to notify rejected credentials")
+		return
+	}
+
+	message := tr(guildLang(guildID), "wclogs.disabled")
+	for _, channelID := range channelIDs {
+		_, err := s.ChannelMessageSend(channelID, message)
+		if err != nil {
+			log.Error().Err(err).Str("guildID", guildID).Str("channelID", channelID).Msg("Failed to notify rejected credentials")
+		}
+	}
+}
+
+// adminChannelIDs returns the announcement channels of a guild tracked characters, or its system channel if none
+func adminChannelIDs(guildID string) []string {
+	var channelIDs []string
+	characters, err := fetchWCLogsTrackedCharacters(db, guildID)
 	if err != nil {
-		log.Warn().Err(err).Msg("No currently tracked characters")
-		characters := make([]*TrackedCharacter, 0)
-		trackedCharacters[guildID] = characters
+		log.Warn().Err(err).Str("guildID", guildID).Msg("No currently tracked characters")
+	}
+	for _, c := range characters {
+		if c.ChannelID != "" && !arrayContains(channelIDs, c.ChannelID) {
+			channelIDs = append(channelIDs, c.ChannelID)
+		}
 	}
 
-	// Setup tracking timer
-	setupWCLogsTicker(guildID)
+	if len(channelIDs) == 0 && s != nil {
+		if guild, err := s.State.Guild(guildID); err == nil && guild.SystemChannelID != "" {
+			channelIDs = append(channelIDs, guild.SystemChannelID)
+		}
+	}
+
+	return channelIDs
+}
+
+// newWCLogs instantiates a WCLogs client with metrics observer
+func newWCLogs(creds *wclogs.Credentials) *wclogs.WCLogs {
+	w := wclogs.New(creds, wclogs.Classic, nil)
+	w.SetRequestObserver(observeWCLogsRequest)
+
+	return w
 }
 
 // destroyWCLogsForGuild unregisters WCLogs credentials, deletes live tracks and remove timers & tickers
 func destroyWCLogsForGuild(guildID string) {
 	log.Debug().Str("guildID", guildID).Msg("destroyWCLogsForGuild")
-	// Remove tracking timer
-	if characterTrackTicker[guildID] != nil {
-		characterTrackTicker[guildID].Stop()
+	// Remove tracking timer and interrupt in-flight requests
+	guildsMutex.Lock()
+	stopWCLogsTicker(guildID)
+	cancelWCLogsContext(guildID)
+	delete(logs, guildID)
+	guildsMutex.Unlock()
+
+	rosterMutex.Lock()
+	delete(trackedCharacters, guildID)
+	rosterMutex.Unlock()
+
+	deleteGuildMetrics(guildID)
+	forgetTick(guildID)
+}
+
+// guildWCLogs returns a guild WCLogs instance, nil if none is registered
+func guildWCLogs(guildID string) *wclogs.WCLogs {
+	guildsMutex.RLock()
+	defer guildsMutex.RUnlock()
+
+	return logs[guildID]
+}
+
+// setGuildWCLogs registers a guild WCLogs instance along with its context
+func setGuildWCLogs(guildID string, w *wclogs.WCLogs) {
+	guildsMutex.Lock()
+	defer guildsMutex.Unlock()
+
+	logs[guildID] = w
+	startWCLogsContext(guildID)
+}
+
+// startWCLogsContext creates a guild wclogsCtx derived from requestsCtx, cancelling the previous one
+// guildsMutex must be held
+func startWCLogsContext(guildID string) context.Context {
+	if wclogsCtx == nil {
+		wclogsCtx = make(map[string]context.Context)
 	}
-	if timerStopper[guildID] != nil {
-		timerStopper[guildID] <- true
+	if wclogsCancel == nil {
+		wclogsCancel = make(map[string]context.CancelFunc)
 	}
 
-	trackedCharacters[guildID] = nil
-	logs[guildID] = nil
+	cancelWCLogsContext(guildID)
+	ctx, cancel := context.WithCancel(requestsCtx)
+	wclogsCtx[guildID] = ctx
+	wclogsCancel[guildID] = cancel
+
+	return ctx
 }
 
-// registerWarcraftLogs instantiates a new WCLogs with credentials for a specific guildID
-func registerWarcraftLogs(clientID, clientSecret, guildID string) string {
-	log.Debug().Str("guildID", guildID).Msg("registerWarcraftLogs")
-	creds := &wclogs.Credentials{ClientID: clientID, ClientSecret: clientSecret}
-	w := wclogs.New(creds, wclogs.Classic, nil)
-	if !w.Connect() {
-		return "These API credentials cannot be used"
+// cancelWCLogsContext interrupts in-flight requests of a guild WCLogs instance
+// guildsMutex must be held
+func cancelWCLogsContext(guildID string) {
+	if wclogsCancel[guildID] != nil {
+		wclogsCancel[guildID]()
+		delete(wclogsCancel, guildID)
 	}
+	delete(wclogsCtx, guildID)
+}
 
-	log.Info().Str("guildID", guildID).Msg("WCLogs instance successful")
-	logs[guildID] = w
+// wclogsContext returns the context of a guild WCLogs requests, requestsCtx if the guild has no instance
+func wclogsContext(guildID string) context.Context {
+	guildsMutex.RLock()
+	defer guildsMutex.RUnlock()
 
-	if trackedCharacters == nil {
-		trackedCharacters = make(map[string][]*TrackedCharacter)
+	if ctx := wclogsCtx[guildID]; ctx != nil {
+		return ctx
 	}
-	var err error
-	trackedCharacters[guildID], err = fetchWCLogsTrackedCharacters(db, guildID)
-	if err != nil {
-		log.Warn().Err(err).Msg("No currently tracked characters")
-		characters := make([]*TrackedCharacter, 0)
-		trackedCharacters[guildID] = characters
+
+	return requestsCtx
+}
+
+// registerWarcraftLogs instantiates a new WCLogs with credentials for a specific guildID
+func registerWarcraftLogs(clientID, clientSecret, guildID string, lang Lang) string {
+	log.Debug().Str("guildID", guildID).Msg("registerWarcraftLogs")
+	creds := &wclogs.Credentials{ClientID: clientID, ClientSecret: clientSecret}
+	w := newWCLogs(creds)
+	if err := w.Connect(requestsCtx); err != nil {
+		log.Warn().Err(err).Str("guildID", guildID).Msg("Connect failed")
+		if errors.Is(err, wclogs.ErrUnauthorized) {
+			return tr(lang, "register.invalid")
+		}
+		return wclogsErrorMessage(err, lang, tr(lang, "register.invalid"))
 	}
 
 	log.Info().Str("guildID", guildID).Msg("WCLogs instance successful")
-	err = storeWCLogsCredentials(db, guildID, creds)
+	setGuildWCLogs(guildID, w)
+	loadTrackedCharacters(guildID)
+
+	err := storeWCLogsCredentials(db, guildID, creds)
 	if err != nil {
 		log.Error().Str("guildID", guildID).Err(err).Msg("storeWCLogsCredentials failed")
-		return "API credentials are valid, but I failed to store them"
+		return tr(lang, "register.store-failed")
 	}
+	setCredentialsRejected(guildID, false)
 
 	// Setup tracking timer
 	setupWCLogsTicker(guildID)
 
-	return "Congrats, API credentials are valid"
+	return tr(lang, "register.success")
 }
 
 // unregisterWarcraftLogs destroys WCLogs instance
-func unregisterWarcraftLogs(guildID string) string {
+func unregisterWarcraftLogs(guildID string, lang Lang) string {
 	log.Debug().Str("guildID", guildID).Msg("unregisterWarcraftLogs")
-	if logs[guildID] == nil {
-		return "No stored credentials"
+	if guildWCLogs(guildID) == nil {
+		return tr(lang, "unregister.missing")
 	}
 
 	destroyWCLogsForGuild(guildID)
 
-	return "Unregister successful"
+	return tr(lang, "unregister.success")
 }
 
 // trackCharacter tries to add a regular performance track on a specific character
-func trackCharacter(name, server, region, guildID, channelID string) string {
+func trackCharacter(name, server, region, guildID, channelID string, lang Lang) string {
 	log.Debug().Str("name", name).Str("server", server).Str("region", "region").
 		Str("guildID", guildID).Str("channelID", channelID).Msg("trackCharacter")
-	if logs[guildID] == nil {
-		return "Missing WarcraftLogs credentials setup"
-	}
-
-	char, err := logs[guildID].GetCharacter(name, server, region)
-	if err != nil || char == nil {
-		log.Error().Str("slug", char.Slug()).Err(err).Msg("GetCharacterID failed")
-		return "Failed to track " + char.Slug() + " : character not found !"
+	w := guildWCLogs(guildID)
+	if w == nil {
+		return tr(lang, "wclogs.missing")
 	}
+	ctx := wclogsContext(guildID)
 
-	for idx, c := range trackedCharacters[guildID] {
-		if c.Character.ID == char.ID {
-			// Remove currently tracked character, it will be added back again later
-			// hackish way to allow update to announce channel
-			trackedCharacters[guildID] = append(trackedCharacters[guildID][:idx], trackedCharacters[guildID][idx+1:]...)
-			log.Warn().Str("slug", char.Slug()).Int("charID", char.ID).Err(err).Msg("Already tracked")
+	char, err := w.GetCharacter(ctx, name, server, region)
+	if err != nil {
+		slug := characterSlug(name, server, region)
+		log.Error().Str("slug", slug).Err(err).Msg("GetCharacterID failed")
+		if errors.Is(err, wclogs.ErrCharacterNotFound) {
+			return tr(lang, "track.not-found", slug)
 		}
+		return wclogsErrorMessage(err, lang, tr(lang, "track.failed", slug))
 	}
 
-	reportMetadata, err := logs[guildID].GetLatestReportMetadata(char)
+	reportMetadata, err := w.GetLatestReportMetadata(ctx, char)
 	if err != nil {
 		log.Error().Str("slug", char.Slug()).Int("charID", char.ID).
 			Err(err).Msg("GetLatestReportMetadata failed")
-		return "Failed to track " + char.Slug() + " : no recent report"
+		if errors.Is(err, wclogs.ErrNoRecentReport) {
+			return tr(lang, "track.no-report", char.Slug())
+		}
+		return wclogsErrorMessage(err, lang, tr(lang, "track.failed", char.Slug()))
 	}
 
 	err = storeWCLogsLatestReportForCharacterID(db, char.ID, reportMetadata)
 	if err != nil {
 		log.Error().Str("slug", char.Slug()).Int("charID", char.ID).
 			Err(err).Msg("storeWCLogsLatestReportForCharacterID failed")
-		return "Failed to track " + char.Slug()
+		return tr(lang, "track.failed", char.Slug())
+	}
+
+	rosterMutex.Lock()
+	// Remove currently tracked character, it will be added back again to allow announce channel updates
+	characters, found := withoutTrackedCharacter(trackedCharacters[guildID], char.ID)
+	if found {
+		log.Warn().Str("slug", char.Slug()).Int("charID", char.ID).Msg("Already tracked")
 	}
 
 	trackedChar := &TrackedCharacter{Character: char, ChannelID: channelID}
-	trackedCharacters[guildID] = append(trackedCharacters[guildID], trackedChar)
-	err = storeWCLogsTrackedCharacters(db, guildID, trackedCharacters[guildID])
+	characters = append(characters, trackedChar)
+	err = updateWCLogsTrackedCharacters(db, guildID, characters, nil)
+	if err == nil {
+		trackedCharacters[guildID] = characters
+		trackedCharactersCount.WithLabelValues(guildID).Set(float64(len(characters)))
+	}
+	rosterMutex.Unlock()
 	if err != nil {
 		log.Error().Str("slug", char.Slug()).Int("charID", char.ID).
-			Err(err).Msg("storeWCLogsTrackedCharacters failed")
-		return "Failed to track " + char.Slug()
+			Err(err).Msg("updateWCLogsTrackedCharacters failed")
+		return tr(lang, "track.failed", char.Slug())
 	}
 
 	// Record parses in goroutine as it may be too slow for discord response
-	go func() {
-		_, err := getAndStoreAllWCLogsParsesForCharacter(guildID, trackedChar)
-		if err != nil {
-			log.Error().Err(err).Str("slug", char.Slug()).Msg("Failed to get all parses")
-		}
-	}()
+	if startInFlight() {
+		go func() {
+			defer doneInFlight()
+			_, err := getAndStoreAllWCLogsParsesForCharacter(wclogsContext(guildID), guildID, trackedChar)
+			if err != nil {
+				log.Error().Err(err).Str("slug", char.Slug()).Msg("Failed to get all parses")
+			}
+		}()
+	}
 
 	log.Info().Str("slug", char.Slug()).Msg("Track successful")
-	return char.Slug() + " is now tracked"
+	return tr(lang, "track.success", char.Slug())
 }
 
 // untrackCharacter removes a character for current tracking
-func untrackCharacter(name, server, region, guildID string) string {
+func untrackCharacter(name, server, region, guildID string, lang Lang) string {
 	log.Debug().Str("name", name).Str("server", server).Str("region", "region").
 		Str("guildID", guildID).Msg("untrackCharacter")
-	if logs[guildID] == nil {
-		return "Missing WarcraftLogs credentials setup"
+	w := guildWCLogs(guildID)
+	if w == nil {
+		return tr(lang, "wclogs.missing")
 	}
+	ctx := wclogsContext(guildID)
 
-	char, err := logs[guildID].GetCharacter(name, server, region)
+	char, err := w.GetCharacter(ctx, name, server, region)
 	if err != nil {
-		log.Error().Str("slug", char.Slug()).Err(err).Msg("GetCharacterID failed")
-		return "Failed to untrack " + char.Slug() + " : character not found !"
+		slug := characterSlug(name, server, region)
+		log.Error().Str("slug", slug).Err(err).Msg("GetCharacterID failed")
+		if errors.Is(err, wclogs.ErrCharacterNotFound) {
+			return tr(lang, "untrack.not-found", slug)
+		}
+		return wclogsErrorMessage(err, lang, tr(lang, "untrack.failed", slug))
 	}
 
-	for idx, c := range trackedCharacters[guildID] {
-		if c.Character.ID == char.ID {
-			trackedCharacters[guildID] = append(trackedCharacters[guildID][:idx], trackedCharacters[guildID][idx+1:]...)
-			log.Info().Str("slug", char.Slug()).Msg("Untrack successful")
-			return char.Slug() + " is not tracked anymore"
+	return removeTrackedCharacter(guildID, char, lang)
+}
+
+// removeTrackedCharacter removes a known character from a guild tracked characters
+func removeTrackedCharacter(guildID string, char *wclogs.Character, lang Lang) string {
+	rosterMutex.Lock()
+	defer rosterMutex.Unlock()
+
+	characters, found := withoutTrackedCharacter(trackedCharacters[guildID], char.ID)
+	if !found {
+		log.Warn().Str("slug", char.Slug()).Msg("Not tracked")
+		return tr(lang, "untrack.not-tracked", char.Slug())
+	}
+
+	err := updateWCLogsTrackedCharacters(db, guildID, characters, []int{char.ID})
+	if err != nil {
+		log.Error().Str("slug", char.Slug()).Int("charID", char.ID).
+			Err(err).Msg("updateWCLogsTrackedCharacters failed")
+		return tr(lang, "untrack.failed", char.Slug())
+	}
+
+	trackedCharacters[guildID] = characters
+	trackedCharactersCount.WithLabelValues(guildID).Set(float64(len(characters)))
+	log.Info().Str("slug", char.Slug()).Msg("Untrack successful")
+	return tr(lang, "untrack.success", char.Slug())
+}
+
+// characterSlug formats a character slug from user input, when the character is not known yet
+func characterSlug(name, server, region string) string {
+	return (&wclogs.Character{Name: name, Server: server, Region: region}).Slug()
+}
+
+// wclogsErrorMessage explains a WarcraftLogs API failure to users, fallback if it is not a known failure
+func wclogsErrorMessage(err error, lang Lang, fallback string) string {
+	switch {
+	case errors.Is(err, wclogs.ErrRateLimited):
+		return tr(lang, "wclogs.rate-limited")
+	case errors.Is(err, wclogs.ErrUnauthorized):
+		return tr(lang, "wclogs.unauthorized")
+	case errors.Is(err, wclogs.ErrTransient):
+		return tr(lang, "wclogs.unavailable")
+	}
+
+	return fallback
+}
+
+// findTrackedCharacter returns a guild tracked character, or nil if not tracked
+func findTrackedCharacter(guildID string, charID int) *TrackedCharacter {
+	for _, c := range guildTrackedCharacters(guildID) {
+		if c.ID == charID {
+			return c
+		}
+	}
+
+	return nil
+}
+
+// withoutTrackedCharacter returns a copy of characters without charID, and whether it was found
+func withoutTrackedCharacter(characters []*TrackedCharacter, charID int) ([]*TrackedCharacter, bool) {
+	found := false
+	filtered := make([]*TrackedCharacter, 0, len(characters))
+	for _, c := range characters {
+		if c.ID == charID {
+			found = true
+			continue
+		}
+		filtered = append(filtered, c)
+	}
+
+	return filtered, found
+}
+
+// guildTrackedCharacters returns a guild tracked characters
+// Roster slices are replaced on each mutation and never modified in place, the returned slice can be read without lock
+func guildTrackedCharacters(guildID string) []*TrackedCharacter {
+	rosterMutex.Lock()
+	defer rosterMutex.Unlock()
+
+	return trackedCharacters[guildID]
+}
+
+// getParsesForCharacter returns all parses for a character, stored ones if tracked or live ones otherwise
+func getParsesForCharacter(name, server, region, guildID string, lang Lang) (*wclogs.Character, *wclogs.Parses, string) {
+	log.Debug().Str("name", name).Str("server", server).Str("region", region).
+		Str("guildID", guildID).Msg("getParsesForCharacter")
+	w := guildWCLogs(guildID)
+	if w == nil {
+		return nil, nil, tr(lang, "wclogs.missing")
+	}
+	ctx := wclogsContext(guildID)
+
+	char, err := w.GetCharacter(ctx, name, server, region)
+	if err != nil {
+		log.Error().Err(err).Str("name", name).Msg("GetCharacter failed")
+		if errors.Is(err, wclogs.ErrCharacterNotFound) {
+			return nil, nil, tr(lang, "parses.not-found", name)
 		}
+		return nil, nil, wclogsErrorMessage(err, lang, tr(lang, "parses.failed", characterSlug(name, server, region)))
 	}
 
-	log.Warn().Str("slug", char.Slug()).Err(err).Msg("Not tracked")
-	return char.Slug() + " was not tracked"
+	if trackedChar := findTrackedCharacter(guildID, char.ID); trackedChar != nil {
+		parses, err := fetchWCLogsParsesForCharacterID(db, char.ID)
+		if err == nil {
+			return trackedChar.Character, parses, ""
+		}
+		log.Warn().Err(err).Str("slug", char.Slug()).Msg("Missing stored parses for tracked character")
+		char = trackedChar.Character
+	}
+
+	parses, err := w.GetParsesForCharacter(ctx, char)
+	if err != nil {
+		log.Error().Err(err).Str("slug", char.Slug()).Msg("GetParsesForCharacter failed")
+		return nil, nil, wclogsErrorMessage(err, lang, tr(lang, "parses.failed", char.Slug()))
+	}
+
+	return char, parses, ""
+}
+
+// TrackedCharacterActivity represents a tracked character along with its latest known report, nil if none
+type TrackedCharacterActivity struct {
+	*TrackedCharacter
+	LatestReport *wclogs.ReportMetadata
 }
 
-// getTrackedCharacters returns an array of all known and tracked characters for a guildID
-func getTrackedCharacters(guildID string) ([]*TrackedCharacter, string) {
-	log.Debug().Str("guildID", guildID).Msg("listTrackedCharacters")
-	if logs[guildID] == nil {
-		return nil, "Missing WarcraftLogs credentials setup"
+// getTrackedCharacters returns all tracked characters for a guildID, most recently active first
+func getTrackedCharacters(guildID string, lang Lang) ([]TrackedCharacterActivity, string) {
+	log.Debug().Str("guildID", guildID).Msg("getTrackedCharacters")
+	if guildWCLogs(guildID) == nil {
+		return nil, tr(lang, "wclogs.missing")
 	}
 
-	return trackedCharacters[guildID], ""
+	var chars []TrackedCharacterActivity
+	for _, char := range guildTrackedCharacters(guildID) {
+		activity := TrackedCharacterActivity{TrackedCharacter: char}
+		if report, err := fetchWCLogsLatestReportForCharacterID(db, char.ID); err == nil {
+			activity.LatestReport = report
+		}
+		chars = append(chars, activity)
+	}
+
+	sort.SliceStable(chars, func(a, b int) bool {
+		reportA, reportB := chars[a].LatestReport, chars[b].LatestReport
+		if reportA == nil || reportB == nil {
+			// Characters without any known report come last
+			if reportA != reportB {
+				return reportB == nil
+			}
+			return chars[a].Slug() < chars[b].Slug()
+		}
+
+		return reportA.EndTime.After(reportB.EndTime)
+	})
+
+	return chars, ""
 }
 
 // getAndStoreAllWCLogsParsesForCharacter gets all available parses for a character and stores them in db
-func getAndStoreAllWCLogsParsesForCharacter(guildID string, char *TrackedCharacter) (*wclogs.Parses, error) {
+func getAndStoreAllWCLogsParsesForCharacter(ctx context.Context, guildID string, char *TrackedCharacter) (*wclogs.Parses, error) {
 	log.Debug().Str("guildID", guildID).Msg("getAndStoreAllWCLogsParsesForCharacter")
-	parses, err := logs[guildID].GetParsesForCharacter(char.Character)
+	w := guildWCLogs(guildID)
+	if w == nil {
+		return nil, errWCLogsMissing
+	}
+	parses, err := w.GetParsesForCharacter(ctx, char.Character)
 	if err != nil {
 		return nil, err
 	}
@@ -254,14 +577,22 @@ func getAndStoreAllWCLogsParsesForCharacter(guildID string, char *TrackedCharact
 }
 
 // checkWCLogsForCharacterUpdates gets the latest report metadata and updates parses if necessary
-func checkWCLogsForCharacterUpdates(guildID string, char *TrackedCharacter) error {
+func checkWCLogsForCharacterUpdates(ctx context.Context, guildID string, char *TrackedCharacter) error {
 	log.Debug().Int("charID", char.ID).Str("slug", char.Slug()).Msg("checkWCLogsForCharacterUpdates")
+	w := guildWCLogs(guildID)
+	if w == nil {
+		return errWCLogsMissing
+	}
+
 	// Get the latest report metadata from DB
 	dbReport, err := fetchWCLogsLatestReportForCharacterID(db, char.ID)
 	if err != nil || dbReport == nil {
 		// Missing initial report
 		log.Debug().Int("charID", char.ID).Str("slug", char.Slug()).Msg("Missing initial report")
-		report, err := logs[guildID].GetLatestReportMetadata(char.Character)
+		report, err := w.GetLatestReportMetadata(ctx, char.Character)
+		if errors.Is(err, wclogs.ErrNoRecentReport) {
+			return nil
+		}
 		if err != nil {
 			return err
 		}
@@ -269,7 +600,7 @@ func checkWCLogsForCharacterUpdates(guildID string, char *TrackedCharacter) erro
 	}
 
 	// Get the latest report metadata from WCLogs
-	report, err := logs[guildID].GetLatestReportMetadata(char.Character)
+	report, err := w.GetLatestReportMetadata(ctx, char.Character)
 	if err != nil {
 		return err
 	}
@@ -288,26 +619,25 @@ func checkWCLogsForCharacterUpdates(guildID string, char *TrackedCharacter) erro
 	if err != nil || dbParses == nil {
 		// Missing initial parses
 		log.Warn().Int("charID", char.ID).Str("slug", char.Slug()).Msg("Missing initial parses")
-		dbParses, err = getAndStoreAllWCLogsParsesForCharacter(guildID, char)
+		dbParses, err = getAndStoreAllWCLogsParsesForCharacter(ctx, guildID, char)
 		if err != nil {
 			return err
 		}
 	}
 
 	// Get the full report from WCLogs
-	fullReport, err := logs[guildID].GetReport(report.Code)
+	fullReport, err := w.GetReport(ctx, report.Code)
 	if err != nil {
 		return err
 	}
 
 	log.Info().Int("charID", char.ID).Str("slug", char.Slug()).Str("code", report.Code).
 		Int64("endTime", fullReport.EndTime.UnixMilli()).Int64("dbEndTime", dbReport.EndTime.UnixMilli()).
-		Int("zoneID", int(fullReport.ZoneID)).Int("size", int(fullReport.Size)).
-		Int("players", len(fullReport.Characters)).Msg("Latest report changes")
+		Interface("raids", fullReport.Raids).Int("players", len(fullReport.Characters)).Msg("Latest report changes")
 
 	var charsInReport []*TrackedCharacter
 	// Scan report for any tracked characters
-	for _, c := range trackedCharacters[guildID] {
+	for _, c := range guildTrackedCharacters(guildID) {
 		for _, charID := range fullReport.Characters {
 			// Tracked char found
 			if c.ID == charID {
@@ -323,146 +653,469 @@ func checkWCLogsForCharacterUpdates(guildID string, char *TrackedCharacter) erro
 			Msg("New report code")
 
 		// Current report has to be older than stored one, anything else might indicate wclogs deletion
-		if report.EndTime.After(dbReport.EndTime) {
-			announceNewReport(report, charsInReport)
+		if report.EndTime.After(dbReport.EndTime) && len(charsInReport) > 0 {
+			// TODO: Channel ID should be a Guild param, not per TrackedCharacter
+			notify(&NewReportEvent{
+				GuildID:    guildID,
+				ChannelID:  charsInReport[0].ChannelID,
+				ReportCode: report.Code,
+				EndTime:    report.EndTime,
+				Characters: charsInReport,
+			})
 		}
 	}
 
+	// New partitions are announced once per zone, with every character whose rankings moved to it
+	var partitionEvents []*NewPartitionEvent
+	// New roles of each character, the roster is updated once every character is checked
+	charsRoles := make(map[int][]wclogs.Role)
+	// The latest report is stored once a character parses are saved, an interrupted check is retried on next tick
 	for _, c := range charsInReport {
-		// Store new report in DB
-		err = storeWCLogsLatestReportForCharacterID(db, c.ID, report)
-		if err != nil {
-			return err
+		// Record roles played in this report, only their metrics are queried and announced
+		if roles := wclogs.MergeRoles(c.Roles, fullReport.Roles(c.ID)); len(roles) != len(c.Roles) {
+			log.Debug().Int("charID", c.ID).Str("slug", c.Slug()).Interface("roles", roles).Msg("New roles played")
+			c = c.withRoles(roles)
+			charsRoles[c.ID] = roles
 		}
 
-		// Get report zone/size specific parses from WCLogs
-		metricRankings, err := logs[guildID].GetMetricRankingsForCharacter(c.Character, fullReport.ZoneID, fullReport.Size)
+		// Get parses from DB
+		dbParses, err := fetchWCLogsParsesForCharacterID(db, c.ID)
+		if err != nil || dbParses == nil {
+			// Missing initial parses, nothing to compare against as they already include this report
+			log.Warn().Int("charID", c.ID).Str("slug", c.Slug()).Msg("Missing initial parses")
+			_, err = getAndStoreAllWCLogsParsesForCharacter(ctx, guildID, c)
+			if err != nil {
+				return err
+			}
+			if err = storeWCLogsLatestReportForCharacterID(db, c.ID, report); err != nil {
+				return err
+			}
+			continue
+		}
+		// Legacy rankings are stored under their difficulty along with this report rankings
+		dbParses.ResolveUnknownDifficulty(w.Zones())
+
+		// Each zone/difficulty/size played in the report is ranked separately
+		for _, raid := range fullReport.CharacterRaids(c.ID) {
+			metricRankings, err := w.GetMetricRankingsForCharacter(ctx, c.Character, raid.ZoneID, raid.Difficulty, raid.Size, c.Metrics())
+			if err != nil {
+				return err
+			}
+
+			// Compare and announce if necessary
+			zone := w.GetZone(raid.ZoneID)
+			if compareParsesAndAnnounce(guildID, metricRankings, dbParses, fullReport, raid, zone, c) {
+				partitionEvents = addNewPartitionCharacter(partitionEvents, guildID, raid.ZoneID, zone, metricRankings.Partition(), c)
+			}
+
+			// Merge parses, following raids of the same zone are compared against the new partition
+			dbParses.MergeMetricRankings(raid.ZoneID, raid.Difficulty, raid.Size, metricRankings)
+		}
+
+		err = storeWCLogsParsesForCharacterID(db, c.ID, dbParses)
 		if err != nil {
 			return err
 		}
 
-		// Get parses from DB
-		dbParses, err := fetchWCLogsParsesForCharacterID(db, c.ID)
+		err = storeWCLogsLatestReportForCharacterID(db, c.ID, report)
 		if err != nil {
 			return err
 		}
+	}
 
-		// Compare and announce if necessary
-		compareParsesAndAnnounce(metricRankings, dbParses, fullReport, c)
-
-		// Merge parses into DB
-		dbParses.MergeMetricRankings(fullReport.ZoneID, fullReport.Size, metricRankings)
-		err = storeWCLogsParsesForCharacterID(db, c.ID, dbParses)
-		if err != nil {
+	if len(charsRoles) > 0 {
+		if err = updateTrackedCharactersRoles(guildID, charsRoles); err != nil {
 			return err
 		}
 	}
 
+	for _, event := range partitionEvents {
+		notify(event)
+	}
+
 	return nil
 }
 
+// addNewPartitionCharacter adds a character to the new partition event of a zone, created if missing
+func addNewPartitionCharacter(events []*NewPartitionEvent, guildID string, zoneID wclogs.ZoneID, zone *wclogs.Zone, partition wclogs.Partition, char *TrackedCharacter) []*NewPartitionEvent {
+	var event *NewPartitionEvent
+	for _, e := range events {
+		if e.ZoneID == zoneID {
+			event = e
+		}
+	}
+
+	if event == nil {
+		event = &NewPartitionEvent{
+			GuildID:   guildID,
+			ChannelID: char.ChannelID,
+			ZoneID:    zoneID,
+			Partition: partition,
+		}
+		if zone != nil {
+			event.ZoneName = zone.Name
+		}
+		events = append(events, event)
+	}
+
+	for _, c := range event.Characters {
+		if c.ID == char.ID {
+			return events
+		}
+	}
+	event.Characters = append(event.Characters, char)
+
+	return events
+}
+
 // announceNewReport formats and sends a new report announcement
-func announceNewReport(report *wclogs.ReportMetadata, chars []*TrackedCharacter) {
-	log.Debug().Str("code", report.Code).Int("chars", len(chars)).Msg("announceNewReport")
-	link := "https://classic.warcraftlogs.com/reports/" + report.Code
+func announceNewReport(event *NewReportEvent, lang Lang) error {
+	log.Debug().Str("code", event.ReportCode).Int("chars", len(event.Characters)).Msg("announceNewReport")
+	link := reportsURL + event.ReportCode
 
 	var charSlugs []string
-	for _, c := range chars {
+	for _, c := range event.Characters {
 		charSlugs = append(charSlugs, c.Slug())
 	}
 
-	// TODO: Channel ID should be a Guild param, not per TrackedCharacter
-	_, err := s.ChannelMessageSendEmbed(chars[0].ChannelID, &discordgo.MessageEmbed{
+	return sendAnnouncement(string(event.Type()), event.GuildID, event.ChannelID, nil, []discordgo.MessageComponent{
+		discordgo.Button{Label: tr(lang, "button.open-report"), Style: discordgo.LinkButton, URL: link},
+	}, &discordgo.MessageEmbed{
 		Type:        discordgo.EmbedTypeRich,
 		URL:         link,
-		Title:       "New report found",
+		Title:       tr(lang, "announce.new-report"),
 		Description: strings.Join(charSlugs, "\n"),
 		Color:       0x904400,
 		Footer: &discordgo.MessageEmbedFooter{
 			Text: link,
 		},
 	})
-	if err != nil {
-		log.Error().Err(err).Msg("Failed to send message")
+}
+
+// sendAnnouncement sends an announcement embed to channelID, with an optional row of buttons
+// Announcements are sent through the channel webhook under the author name if enabled for the guild
+func sendAnnouncement(announcement, guildID, channelID string, author *wclogs.Character, buttons []discordgo.MessageComponent, embed *discordgo.MessageEmbed) error {
+	var components []discordgo.MessageComponent
+	if len(buttons) > 0 {
+		components = []discordgo.MessageComponent{discordgo.ActionsRow{Components: buttons}}
 	}
+
+	if webhookAnnouncementsEnabled(guildID) {
+		params := &discordgo.WebhookParams{Embeds: []*discordgo.MessageEmbed{embed}, Components: components}
+		if author != nil {
+			params.Username = author.Name
+			params.AvatarURL = classAvatarURL(author)
+		}
+
+		err := sendWebhookAnnouncement(guildID, channelID, params)
+		if !isDiscordError(err, discordgo.ErrCodeMissingPermissions, discordgo.ErrCodeMissingAccess) {
+			observeAnnouncement(announcement, err)
+			return err
+		}
+
+		log.Warn().Err(err).Str("guildID", guildID).Str("channelID", channelID).Msg("Missing webhook permissions, sending as bot")
+	}
+
+	_, err := s.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
+		Embeds:     []*discordgo.MessageEmbed{embed},
+		Components: components,
+	})
+	observeAnnouncement(announcement, err)
+	return err
 }
 
-// compareParsesAndAnnounce iterates over rankings to find a new parse and announce it there is an improvement
-func compareParsesAndAnnounce(metricRankings *wclogs.MetricRankings, dbParses *wclogs.Parses, report *wclogs.Report, char *TrackedCharacter) {
-	log.Debug().Str("code", report.Code).Str("slug", char.Slug()).Msg("compareParsesAndAnnounce")
-	if (*dbParses)[report.ZoneID] == nil || (*dbParses)[report.ZoneID][report.Size] == nil {
-		return
+// updateTrackedCharactersRoles replaces the roster of a guild with copies of its characters with new roles
+// Characters untracked meanwhile are not added back
+func updateTrackedCharactersRoles(guildID string, charsRoles map[int][]wclogs.Role) error {
+	rosterMutex.Lock()
+	defer rosterMutex.Unlock()
+
+	characters := make([]*TrackedCharacter, 0, len(trackedCharacters[guildID]))
+	for _, c := range trackedCharacters[guildID] {
+		if roles, ok := charsRoles[c.ID]; ok {
+			c = c.withRoles(wclogs.MergeRoles(c.Roles, roles))
+		}
+		characters = append(characters, c)
+	}
+
+	if err := storeWCLogsTrackedCharacters(db, guildID, characters); err != nil {
+		return err
+	}
+	trackedCharacters[guildID] = characters
+
+	return nil
+}
+
+// announceNewPartition formats and sends a new partition announcement, previous parses are not compared anymore
+func announceNewPartition(event *NewPartitionEvent, lang Lang) error {
+	log.Debug().Int("zoneID", int(event.ZoneID)).Int("partition", int(event.Partition)).
+		Int("chars", len(event.Characters)).Msg("announceNewPartition")
+
+	var charSlugs []string
+	for _, c := range event.Characters {
+		charSlugs = append(charSlugs, c.Slug())
+	}
+
+	zoneName := event.ZoneName
+	if zoneName == "" {
+		zoneName = tr(lang, "announce.unknown-zone")
+	}
+
+	return sendAnnouncement(string(event.Type()), event.GuildID, event.ChannelID, nil, nil, &discordgo.MessageEmbed{
+		Type:        discordgo.EmbedTypeRich,
+		Title:       tr(lang, "announce.new-partition", zoneName),
+		Description: tr(lang, "announce.new-partition-description", event.Partition, strings.Join(charSlugs, "\n")),
+		Color:       0x904400,
+	})
+}
+
+// compareParsesAndAnnounce iterates over rankings of a report raid to find first kills and parse improvements and announce them
+// Returns true if rankings belong to a partition not yet stored for this zone, nothing is compared in that case
+func compareParsesAndAnnounce(guildID string, metricRankings *wclogs.MetricRankings, dbParses *wclogs.Parses, report *wclogs.Report, raid wclogs.ReportRaid, zone *wclogs.Zone, char *TrackedCharacter) bool {
+	log.Debug().Str("code", report.Code).Str("slug", char.Slug()).Int("zoneID", int(raid.ZoneID)).
+		Int("difficulty", int(raid.Difficulty)).Int("size", int(raid.Size)).Msg("compareParsesAndAnnounce")
+	if (*dbParses)[raid.ZoneID] == nil {
+		return false
+	}
+
+	partition := metricRankings.Partition()
+	if !dbParses.HasPartition(raid.ZoneID, partition) {
+		log.Info().
+			Str("slug", char.Slug()).Int("charID", char.ID).
+			Str("code", report.Code).Int("zoneID", int(raid.ZoneID)).
+			Int("partition", int(partition)).Msg("New partition")
+		return true
+	}
+
+	dbMetricRankings := dbParses.Get(raid.ZoneID, partition, raid.Difficulty, raid.Size)
+	if dbMetricRankings == nil {
+		return false
 	}
 
 	for metric, rankings := range *metricRankings {
+		// Metric never stored, such as a newly played role, nothing to compare against
+		dbRankings, ok := dbMetricRankings[metric]
+		if !ok {
+			continue
+		}
+
 		for _, ranking := range rankings.Rankings {
-			for _, dbRanking := range (*dbParses)[report.ZoneID][report.Size][metric].Rankings {
-				if ranking.Encounter.ID == dbRanking.Encounter.ID {
-					if ranking.RankPercent-dbRanking.RankPercent > 0.1 {
-						log.Info().
-							Str("slug", char.Slug()).Int("charID", char.ID).
-							Str("code", report.Code).Str("encounter", ranking.Encounter.Name).
-							Str("metric", string(metric)).Float64("oldParse", dbRanking.RankPercent).
-							Float64("newParse", ranking.RankPercent).Msg("New parse")
-
-						announceParse(&ranking, &dbRanking, report, metric, char)
-					}
+			if ranking.RankPercent <= 0 {
+				continue
+			}
+
+			var dbRanking *wclogs.Ranking
+			for idx := range dbRankings.Rankings {
+				if dbRankings.Rankings[idx].Encounter.ID == ranking.Encounter.ID {
+					dbRanking = &dbRankings.Rankings[idx]
 				}
 			}
+
+			if dbRanking == nil || dbRanking.RankPercent <= 0 {
+				log.Info().
+					Str("slug", char.Slug()).Int("charID", char.ID).
+					Str("code", report.Code).Str("encounter", ranking.Encounter.Name).
+					Str("metric", string(metric)).Float64("newParse", ranking.RankPercent).Msg("First kill")
+
+				notify(&FirstKillEvent{ParseEvent: newParseEvent(guildID, report, raid, zone, metric, &ranking, char)})
+			} else if ranking.RankPercent-dbRanking.RankPercent > parseImprovementThreshold {
+				log.Info().
+					Str("slug", char.Slug()).Int("charID", char.ID).
+					Str("code", report.Code).Str("encounter", ranking.Encounter.Name).
+					Str("metric", string(metric)).Float64("oldParse", dbRanking.RankPercent).
+					Float64("newParse", ranking.RankPercent).Msg("New parse")
+
+				notify(&NewParseEvent{
+					ParseEvent:          newParseEvent(guildID, report, raid, zone, metric, &ranking, char),
+					PreviousRankPercent: dbRanking.RankPercent,
+				})
+			}
 		}
 	}
+
+	return false
 }
 
-// announceParse formats and sends a new parse announcement
-func announceParse(ranking *wclogs.Ranking, dbRanking *wclogs.Ranking, report *wclogs.Report, metric wclogs.Metric, char *TrackedCharacter) {
-	log.Debug().Str("code", report.Code).Str("slug", char.Slug()).Msg("announceParse")
-	// TODO: Get player spec and fight ID for proper link
-	link := "https://classic.warcraftlogs.com/reports/" + report.Code
-	reaction := goodParse[rand.Intn(len(goodParse))]
-	if ranking.RankPercent < 50 {
-		reaction = badParse[rand.Intn(len(badParse))]
+// parseReaction returns a random reaction to a parse, mocking under badParseThreshold
+func parseReaction(rankPercent float64, lang Lang) string {
+	if rankPercent >= badParseThreshold {
+		return goodParse[rand.Intn(len(goodParse))]
+	}
+
+	quips, ok := badParse[lang]
+	if !ok {
+		quips = badParse[defaultLang]
 	}
+	return quips[rand.Intn(len(quips))]
+}
 
-	_, err := s.ChannelMessageSendEmbed(char.ChannelID, &discordgo.MessageEmbed{
+// parseButtons returns parse announcement buttons, the fight link is only added if known
+func parseButtons(event *ParseEvent, lang Lang) []discordgo.MessageComponent {
+	link := reportsURL + event.ReportCode
+	buttons := []discordgo.MessageComponent{
+		discordgo.Button{Label: tr(lang, "button.show-parses"), Style: discordgo.SecondaryButton, CustomID: characterComponentID("show-parses", event.Character.ID)},
+		discordgo.Button{Label: tr(lang, "button.untrack"), Style: discordgo.DangerButton, CustomID: characterComponentID("untrack", event.Character.ID)},
+		discordgo.Button{Label: tr(lang, "button.open-report"), Style: discordgo.LinkButton, URL: link},
+	}
+	if event.FightID != 0 {
+		buttons = append(buttons, discordgo.Button{
+			Label: tr(lang, "button.open-fight"), Style: discordgo.LinkButton, URL: fmt.Sprintf("%s#fight=%d", link, event.FightID),
+		})
+	}
+
+	return buttons
+}
+
+// announceParse formats and sends a new parse announcement
+func announceParse(event *NewParseEvent, lang Lang) error {
+	char := event.Character
+	log.Debug().Str("code", event.ReportCode).Str("slug", char.Slug()).Msg("announceParse")
+
+	return sendAnnouncement(string(event.Type()), event.GuildID, event.ChannelID, char.Character, parseButtons(&event.ParseEvent, lang), &discordgo.MessageEmbed{
 		Type:  discordgo.EmbedTypeRich,
-		URL:   link,
-		Title: fmt.Sprintf("New parse for %s", char.Slug()),
-		Description: fmt.Sprintf("**%s(%d)** %s : %s :arrow_right: **%s** %s",
-			ranking.Encounter.Name, report.Size, metric.Emoji(),
-			fmt.Sprintf("%.2f", dbRanking.RankPercent), fmt.Sprintf("%.2f", ranking.RankPercent), reaction),
+		URL:   reportsURL + event.ReportCode,
+		Title: tr(lang, "announce.new-parse", char.Slug()),
+		Description: fmt.Sprintf("**%s(%d %s)** %s : %s :arrow_right: **%s** %s",
+			event.EncounterName, event.Size, event.DifficultyName, event.Metric.Emoji(),
+			fmt.Sprintf("%.2f", event.PreviousRankPercent), fmt.Sprintf("%.2f", event.RankPercent),
+			parseReaction(event.RankPercent, lang)),
+	})
+}
+
+// announceFirstKill formats and sends a first kill announcement
+func announceFirstKill(event *FirstKillEvent, lang Lang) error {
+	char := event.Character
+	log.Debug().Str("code", event.ReportCode).Str("slug", char.Slug()).Msg("announceFirstKill")
+
+	return sendAnnouncement(string(event.Type()), event.GuildID, event.ChannelID, char.Character, parseButtons(&event.ParseEvent, lang), &discordgo.MessageEmbed{
+		Type:  discordgo.EmbedTypeRich,
+		URL:   reportsURL + event.ReportCode,
+		Title: tr(lang, "announce.first-kill", event.EncounterName, char.Slug()),
+		Description: fmt.Sprintf("**%s(%d %s)** %s : **%s** %s",
+			event.EncounterName, event.Size, event.DifficultyName, event.Metric.Emoji(),
+			fmt.Sprintf("%.2f", event.RankPercent), parseReaction(event.RankPercent, lang)),
+		Color: 0x904400,
 	})
-	if err != nil {
-		log.Error().Err(err).Msg("Failed to send message")
-	}
 }
 
 // setupWCLogsTicker starts the periodic check ticker, including character parses updates
 func setupWCLogsTicker(guildID string) {
+	interval := nextPollInterval(guildID)
+
+	guildsMutex.Lock()
+	defer guildsMutex.Unlock()
 	if characterTrackTicker == nil {
 		characterTrackTicker = make(map[string]*time.Ticker)
 	}
-	if timerStopper == nil {
-		timerStopper = make(map[string]chan bool)
+	if tickerCancel == nil {
+		tickerCancel = make(map[string]context.CancelFunc)
 	}
-	characterTrackTicker[guildID] = time.NewTicker(characterTrackTickerDuration)
-	timerStopper[guildID] = make(chan bool)
+
+	// Never run two tickers for the same guild
+	stopWCLogsTicker(guildID)
+
+	ctx, cancel := context.WithCancel(botCtx)
+	ticker := time.NewTicker(interval)
+	characterTrackTicker[guildID] = ticker
+	tickerCancel[guildID] = cancel
 
 	log.Info().Str("guildID", guildID).Msg("Started ticker")
+	recordTick(guildID)
 
 	go func(guildID string) {
+		defer ticker.Stop()
 		for {
 			select {
-			case <-timerStopper[guildID]:
+			case <-ctx.Done():
+				log.Debug().Str("guildID", guildID).Msg("Stopped ticker")
 				return
-			case <-characterTrackTicker[guildID].C:
-				log.Debug().Str("guildID", guildID).Msg("Tick")
-				for _, char := range trackedCharacters[guildID] {
-					err := checkWCLogsForCharacterUpdates(guildID, char)
-					if err != nil {
-						log.Error().Err(err).Msg("Failed to checkWCLogsForCharacterUpdates in wclogs ticker")
-					}
+			case <-ticker.C:
+				if !startInFlight() {
+					return
 				}
+				tickWCLogs(ctx, guildID)
+				doneInFlight()
+
+				interval := nextPollInterval(guildID)
+				log.Debug().Str("guildID", guildID).Dur("interval", interval).Msg("Next tick")
+				ticker.Reset(interval)
 			}
 		}
 	}(guildID)
 }
+
+// stopWCLogsTicker cancels a guild ticker, a running tick stops after its current character check
+// guildsMutex must be held
+func stopWCLogsTicker(guildID string) {
+	if tickerCancel[guildID] != nil {
+		tickerCancel[guildID]()
+		delete(tickerCancel, guildID)
+	}
+	delete(characterTrackTicker, guildID)
+}
+
+// tickWCLogs checks every tracked character of a guild for updates
+// Cancelling ctx stops the tick after the current character check, its requests are only cancelled with the guild context
+func tickWCLogs(ctx context.Context, guildID string) {
+	reqCtx := wclogsContext(guildID)
+	log.Debug().Str("guildID", guildID).Msg("Tick")
+	ticksTotal.WithLabelValues(guildID).Inc()
+	characters := guildTrackedCharacters(guildID)
+	trackedCharactersCount.WithLabelValues(guildID).Set(float64(len(characters)))
+	for _, char := range characters {
+		if ctx.Err() != nil {
+			log.Debug().Str("guildID", guildID).Msg("Tick interrupted")
+			return
+		}
+
+		start := time.Now()
+		err := checkWCLogsForCharacterUpdates(reqCtx, guildID, char)
+		if reqCtx.Err() != nil {
+			log.Debug().Str("guildID", guildID).Msg("Tick interrupted")
+			return
+		}
+		observeCharacterCheck(guildID, time.Since(start), err)
+		if errors.Is(err, wclogs.ErrUnauthorized) {
+			disableWCLogsForGuild(guildID, err)
+			return
+		}
+		if errors.Is(err, wclogs.ErrRateLimited) {
+			// Remaining characters would fail the same way until the rate limit resets
+			log.Warn().Err(err).Str("guildID", guildID).Msg("Rate limited, skipping remaining characters until next tick")
+			break
+		}
+		if err != nil {
+			log.Error().Err(err).Str("slug", char.Slug()).Msg("Failed to checkWCLogsForCharacterUpdates in wclogs ticker")
+		}
+	}
+	if w := guildWCLogs(guildID); w != nil {
+		updateRateLimitsMetrics(reqCtx, guildID, w)
+	}
+	recordTick(guildID)
+}
+
+// nextPollInterval returns the guild ticker duration, based on the most recent report of tracked characters
+// Recent activity is checked every minPollInterval, one more minute is added for each hour since the latest report end
+func nextPollInterval(guildID string) time.Duration {
+	var latestEndTime time.Time
+	for _, c := range guildTrackedCharacters(guildID) {
+		report, err := fetchWCLogsLatestReportForCharacterID(db, c.ID)
+		if err == nil && report.EndTime.After(latestEndTime) {
+			latestEndTime = report.EndTime
+		}
+	}
+
+	if latestEndTime.IsZero() {
+		return maxPollInterval
+	}
+
+	interval := time.Duration(time.Since(latestEndTime).Hours()) * time.Minute
+	if interval < minPollInterval {
+		return minPollInterval
+	}
+	if interval > maxPollInterval {
+		return maxPollInterval
+	}
+
+	return interval
+}
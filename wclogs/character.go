@@ -2,14 +2,30 @@ package wclogs
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"github.com/machinebox/graphql"
 )
 
-// classIDCanHeal defines a collection of classes capable of healing
+// classIDCanHeal defines a collection of classes capable of healing, only used until a Character played a logged fight
 var classIDCanHeal = []int{2, 5, 6, 7, 9}
 
+// classNames maps WarcraftLogs class IDs to class names
+var classNames = map[int]string{
+	1:  "Death Knight",
+	2:  "Druid",
+	3:  "Hunter",
+	4:  "Mage",
+	5:  "Monk",
+	6:  "Paladin",
+	7:  "Priest",
+	8:  "Rogue",
+	9:  "Shaman",
+	10: "Warlock",
+	11: "Warrior",
+	12: "Demon Hunter",
+	13: "Evoker",
+}
+
 // Character represents character info
 type Character struct {
 	ID      int
@@ -17,6 +33,33 @@ type Character struct {
 	Server  string
 	Region  string
 	ClassID int
+	// Roles contains every role played in processed reports, empty until one is processed
+	// Roles are merged across reports and never expire, off-spec rankings stay worth announcing
+	Roles []Role `json:",omitempty"`
+}
+
+// Role is the raid role played by a character during a fight
+type Role string
+
+const (
+	RoleTank   Role = "tank"
+	RoleHealer Role = "healer"
+	RoleDPS    Role = "dps"
+)
+
+// Metric returns the Metric used to rank a Role
+func (r Role) Metric() Metric {
+	if r == RoleHealer {
+		return MetricHPS
+	}
+
+	return MetricDPS
+}
+
+// PlayerSpec represents the spec and role played by a character during a fight
+type PlayerSpec struct {
+	Spec string
+	Role Role
 }
 
 // Slug returns printable Character identifier
@@ -24,7 +67,16 @@ func (t *Character) Slug() string {
 	return fmt.Sprintf("%s %s-%s", t.Name, t.Region, t.Server)
 }
 
-// CanHeal returns true if Character should also be tracked as a healer
+// ClassName returns Character class name, Unknown if the class ID is not known
+func (t *Character) ClassName() string {
+	if name, ok := classNames[t.ClassID]; ok {
+		return name
+	}
+
+	return "Unknown"
+}
+
+// CanHeal returns true if Character class is capable of healing
 func (t *Character) CanHeal() bool {
 	for _, classID := range classIDCanHeal {
 		if classID == t.ClassID {
@@ -35,8 +87,54 @@ func (t *Character) CanHeal() bool {
 	return false
 }
 
+// Metrics returns the Metric collection matching Character roles, based on class capabilities if no role is known yet
+func (t *Character) Metrics() []Metric {
+	if len(t.Roles) == 0 {
+		if t.CanHeal() {
+			return []Metric{MetricDPS, MetricHPS}
+		}
+		return []Metric{MetricDPS}
+	}
+
+	return RolesMetrics(t.Roles)
+}
+
+// MergeRoles returns roles followed by the played roles missing from it
+func MergeRoles(roles, played []Role) []Role {
+	merged := append([]Role{}, roles...)
+	for _, role := range played {
+		if !rolesContains(merged, role) {
+			merged = append(merged, role)
+		}
+	}
+
+	return merged
+}
+
+func rolesContains(roles []Role, role Role) bool {
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RolesMetrics returns the distinct Metric collection used to rank roles
+func RolesMetrics(roles []Role) []Metric {
+	var metrics []Metric
+	for _, role := range roles {
+		if !metricsContains(metrics, role.Metric()) {
+			metrics = append(metrics, role.Metric())
+		}
+	}
+
+	return metrics
+}
+
 // GetCharacter queries WarcraftLogs character info based on character name, server and server region
-func (w *WCLogs) GetCharacter(name, server, region string) (*Character, error) {
+func (w *WCLogs) GetCharacter(ctx context.Context, name, server, region string) (*Character, error) {
 	req := graphql.NewRequest(`
     query ($name: String!, $server: String!, $region: String!) {
 		characterData {
@@ -75,12 +173,12 @@ func (w *WCLogs) GetCharacter(name, server, region string) (*Character, error) {
 		}
 	}
 
-	if err := w.client.Run(context.Background(), req, &resp); err != nil {
+	if err := w.run(ctx, "GetCharacter", req, &resp); err != nil {
 		return nil, err
 	}
 
 	if resp.CharacterData.Character.ID == 0 {
-		return nil, errors.New("character not found")
+		return nil, ErrCharacterNotFound
 	}
 
 	return &Character{
@@ -0,0 +1,28 @@
+package wclogs
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergeRoles(t *testing.T) {
+	tests := []struct {
+		name   string
+		roles  []Role
+		played []Role
+		want   []Role
+	}{
+		{name: "first report", played: []Role{RoleHealer}, want: []Role{RoleHealer}},
+		{name: "off-spec night", roles: []Role{RoleHealer}, played: []Role{RoleDPS}, want: []Role{RoleHealer, RoleDPS}},
+		{name: "known roles", roles: []Role{RoleHealer, RoleDPS}, played: []Role{RoleDPS}, want: []Role{RoleHealer, RoleDPS}},
+		{name: "no role played", roles: []Role{RoleTank}, want: []Role{RoleTank}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MergeRoles(tt.roles, tt.played); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MergeRoles = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package wclogs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+var (
+	// ErrCharacterNotFound is returned when WarcraftLogs does not know a character
+	ErrCharacterNotFound = errors.New("character not found")
+	// ErrNoRecentReport is returned when a character has not been logged in any report
+	ErrNoRecentReport = errors.New("no recent report")
+	// ErrRateLimited is returned when the API points of the current hour are spent
+	ErrRateLimited = errors.New("rate limited")
+	// ErrUnauthorized is returned when credentials are rejected, usually after a client deletion or secret rotation
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrTransient is returned on network failures and server errors, such requests are retried
+	ErrTransient = errors.New("transient error")
+)
+
+// maxRetries is the count of additional attempts of a request failing with ErrTransient
+const maxRetries = 3
+
+// retryBaseDelay is the backoff delay before the first retry, doubled on each following retry
+var retryBaseDelay = 500 * time.Millisecond
+
+// classifiedError is an API error matching one of the package errors with errors.Is, while keeping its cause
+type classifiedError struct {
+	kind  error
+	cause error
+}
+
+func (e *classifiedError) Error() string {
+	return e.kind.Error() + ": " + e.cause.Error()
+}
+
+func (e *classifiedError) Is(target error) bool {
+	return target == e.kind
+}
+
+func (e *classifiedError) Unwrap() error {
+	return e.cause
+}
+
+// classifyError wraps a request error with ErrUnauthorized, ErrRateLimited or ErrTransient when its cause is known
+func classifyError(err error) error {
+	if err == nil || errors.Is(err, ErrUnauthorized) || errors.Is(err, ErrRateLimited) || errors.Is(err, ErrTransient) {
+		return err
+	}
+
+	// Token requests failures
+	var retrieveErr *oauth2.RetrieveError
+	if errors.As(err, &retrieveErr) && retrieveErr.Response != nil {
+		if kind := statusErrorKind(retrieveErr.Response.StatusCode); kind != nil {
+			return &classifiedError{kind: kind, cause: err}
+		}
+		return &classifiedError{kind: ErrUnauthorized, cause: err}
+	}
+
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+
+	// Every http.Client error is an *url.Error implementing net.Error, as are syscall errors, only network failures are transient
+	cause := err
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		cause = urlErr.Err
+	}
+
+	var opErr *net.OpError
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	if errors.As(cause, &opErr) || errors.As(cause, &dnsErr) || (errors.As(cause, &netErr) && netErr.Timeout()) ||
+		errors.Is(cause, io.ErrUnexpectedEOF) || errors.Is(cause, io.EOF) || errors.Is(cause, syscall.ECONNRESET) {
+		return &classifiedError{kind: ErrTransient, cause: err}
+	}
+
+	return err
+}
+
+// statusErrorKind returns the package error matching an HTTP error status, nil if unknown
+func statusErrorKind(statusCode int) error {
+	switch {
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		return ErrUnauthorized
+	case statusCode == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case statusCode >= http.StatusInternalServerError:
+		return ErrTransient
+	}
+
+	return nil
+}
+
+// retryDelay returns a jittered exponential backoff delay before a retry attempt, starting at 0
+func retryDelay(attempt int) time.Duration {
+	delay := retryBaseDelay << attempt
+	// Full jitter, between half and the whole delay
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// statusTransport turns error HTTP statuses into classified errors, as graphql responses status are not checked
+type statusTransport struct {
+	next http.RoundTripper
+}
+
+func (t *statusTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= http.StatusOK && resp.StatusCode < http.StatusMultipleChoices {
+		return resp, nil
+	}
+
+	_ = resp.Body.Close()
+	cause := fmt.Errorf("server responded with status %s", resp.Status)
+	if kind := statusErrorKind(resp.StatusCode); kind != nil {
+		return nil, &classifiedError{kind: kind, cause: cause}
+	}
+
+	return nil, cause
+}
@@ -0,0 +1,127 @@
+package wclogs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// roundTripFunc answers requests without reaching the network
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestRunClassifiesStatus(t *testing.T) {
+	previousDelay := retryBaseDelay
+	retryBaseDelay = time.Millisecond
+	t.Cleanup(func() { retryBaseDelay = previousDelay })
+
+	tests := []struct {
+		status       int
+		wantErr      error
+		wantAttempts int
+	}{
+		{status: http.StatusUnauthorized, wantErr: ErrUnauthorized, wantAttempts: 1},
+		{status: http.StatusForbidden, wantErr: ErrUnauthorized, wantAttempts: 1},
+		{status: http.StatusTooManyRequests, wantErr: ErrRateLimited, wantAttempts: 1},
+		{status: http.StatusInternalServerError, wantErr: ErrTransient, wantAttempts: maxRetries + 1},
+		{status: http.StatusServiceUnavailable, wantErr: ErrTransient, wantAttempts: maxRetries + 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			attempts := 0
+			transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				attempts++
+				return &http.Response{
+					Status:     http.StatusText(tt.status),
+					StatusCode: tt.status,
+					Body:       io.NopCloser(strings.NewReader(`{"error":"` + http.StatusText(tt.status) + `"}`)),
+					Request:    req,
+				}, nil
+			})
+
+			w := NewWithHTTPClient(Classic, &http.Client{Transport: transport}, nil)
+			_, err := w.GetRateLimits(context.Background())
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("GetRateLimits error = %v, want %v", err, tt.wantErr)
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", attempts, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestRunRetriesUntilSuccess(t *testing.T) {
+	previousDelay := retryBaseDelay
+	retryBaseDelay = time.Millisecond
+	t.Cleanup(func() { retryBaseDelay = previousDelay })
+
+	attempts := 0
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		attempts++
+		if attempts == 1 {
+			return &http.Response{StatusCode: http.StatusBadGateway, Body: io.NopCloser(strings.NewReader("")), Request: req}, nil
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(`{"data":{"rateLimitData":{"limitPerHour":3600}}}`)),
+			Request:    req,
+		}, nil
+	})
+
+	w := NewWithHTTPClient(Classic, &http.Client{Transport: transport}, nil)
+	rateLimits, err := w.GetRateLimits(context.Background())
+	if err != nil {
+		t.Fatalf("GetRateLimits failed: %v", err)
+	}
+	if rateLimits.LimitPerHour != 3600 || attempts != 2 {
+		t.Errorf("LimitPerHour = %d after %d attempts, want 3600 after 2", rateLimits.LimitPerHour, attempts)
+	}
+}
+
+func TestRunClassifiesNetworkErrors(t *testing.T) {
+	previousDelay := retryBaseDelay
+	retryBaseDelay = time.Millisecond
+	t.Cleanup(func() { retryBaseDelay = previousDelay })
+
+	tests := []struct {
+		name         string
+		err          error
+		wantErr      error
+		wantAttempts int
+	}{
+		{name: "connection refused", err: &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}, wantErr: ErrTransient, wantAttempts: maxRetries + 1},
+		{name: "unexpected EOF", err: io.ErrUnexpectedEOF, wantErr: ErrTransient, wantAttempts: maxRetries + 1},
+		{name: "missing fixture", err: fs.ErrNotExist, wantAttempts: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				attempts++
+				return nil, tt.err
+			})
+
+			w := NewWithHTTPClient(Classic, &http.Client{Transport: transport}, nil)
+			_, err := w.GetRateLimits(context.Background())
+			if err == nil || (tt.wantErr != nil && !errors.Is(err, tt.wantErr)) {
+				t.Errorf("GetRateLimits error = %v, want %v", err, tt.wantErr)
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", attempts, tt.wantAttempts)
+			}
+		})
+	}
+}
@@ -2,6 +2,9 @@ package wclogs
 
 import (
 	"context"
+	"fmt"
+	"strings"
+
 	"github.com/machinebox/graphql"
 )
 
@@ -25,6 +28,17 @@ func (f Flavor) String() string {
 	return [...]string{"Retail", "Classic", "Vanilla"}[f]
 }
 
+// ParseFlavor returns the Flavor matching a printable Flavor, ignoring case
+func ParseFlavor(name string) (Flavor, error) {
+	for f := Retail; f <= Vanilla; f++ {
+		if strings.EqualFold(f.String(), name) {
+			return f, nil
+		}
+	}
+
+	return Retail, fmt.Errorf("unknown flavor %q", name)
+}
+
 func (f Flavor) Uri() string {
 	uri := retailApiUri
 	switch f {
@@ -44,7 +58,7 @@ func (f Flavor) Expansion() int {
 }
 
 // getLatestExpansion the latest expansion ID for a specific Flavor
-func (w *WCLogs) getLatestExpansion() (int, error) {
+func (w *WCLogs) getLatestExpansion(ctx context.Context) (int, error) {
 	req := graphql.NewRequest(`
     query {
 		worldData {
@@ -65,7 +79,7 @@ func (w *WCLogs) getLatestExpansion() (int, error) {
 		}
 	}
 
-	if err := w.client.Run(context.Background(), req, &resp); err != nil {
+	if err := w.run(ctx, "getLatestExpansion", req, &resp); err != nil {
 		return 0, err
 	}
 
@@ -9,16 +9,103 @@ import (
 // ZoneID represents the raid cluster zone identifier
 type ZoneID int
 
-// Parses contains SizeRankings for multiple ZoneID
-type Parses map[ZoneID]SizeRankings
+// Parses contains PartitionParses for multiple ZoneID
+type Parses map[ZoneID]PartitionParses
 
-func (p *Parses) MergeMetricRankings(zoneID ZoneID, size RaidSize, rankings *MetricRankings) {
+// MergeMetricRankings stores rankings for a zone, difficulty and raid size, under the partition they were ranked in
+func (p *Parses) MergeMetricRankings(zoneID ZoneID, difficulty Difficulty, size RaidSize, rankings *MetricRankings) {
+	partition := rankings.Partition()
 	if (*p)[zoneID] == nil {
-		(*p)[zoneID] = SizeRankings{}
+		(*p)[zoneID] = PartitionParses{}
 	}
-	(*p)[zoneID][size] = *rankings
+	if (*p)[zoneID][partition] == nil {
+		(*p)[zoneID][partition] = DifficultyRankings{}
+	}
+	if (*p)[zoneID][partition][difficulty] == nil {
+		(*p)[zoneID][partition][difficulty] = SizeRankings{}
+	}
+	if (*p)[zoneID][partition][difficulty][size] == nil {
+		(*p)[zoneID][partition][difficulty][size] = MetricRankings{}
+	}
+	// Merge each metric separately, rankings may not contain every tracked metric
+	for metric, metricRankings := range *rankings {
+		(*p)[zoneID][partition][difficulty][size][metric] = metricRankings
+	}
+}
+
+// Get returns stored MetricRankings for a zone, partition, difficulty and raid size, or nil if unknown
+func (p *Parses) Get(zoneID ZoneID, partition Partition, difficulty Difficulty, size RaidSize) MetricRankings {
+	if !p.HasPartition(zoneID, partition) || (*p)[zoneID][partition][difficulty] == nil {
+		return nil
+	}
+
+	return (*p)[zoneID][partition][difficulty][size]
+}
+
+// HasPartition returns true if any rankings are stored for this zone and partition
+func (p *Parses) HasPartition(zoneID ZoneID, partition Partition) bool {
+	return (*p)[zoneID] != nil && (*p)[zoneID][partition] != nil
+}
+
+// LatestPartition returns the most recent Partition stored for this zone, false if none is stored
+func (p *Parses) LatestPartition(zoneID ZoneID) (Partition, bool) {
+	found := false
+	var latest Partition
+	for partition := range (*p)[zoneID] {
+		if !found || partition > latest {
+			latest = partition
+			found = true
+		}
+	}
+
+	return latest, found
+}
+
+// UnknownDifficulty holds rankings stored before their difficulty was, ranked in the highest difficulty of their zone
+const UnknownDifficulty Difficulty = 0
+
+// ResolveUnknownDifficulty moves UnknownDifficulty rankings under the highest difficulty of their zone,
+// rankings already stored for that difficulty and size are more recent and kept. Returns true if parses changed
+// Rankings of zones missing from zones are left unresolved
+func (p *Parses) ResolveUnknownDifficulty(zones Zones) bool {
+	changed := false
+	for zoneID, partitionParses := range *p {
+		difficulty, ok := zones.GetZone(zoneID).HighestDifficulty()
+		if !ok {
+			continue
+		}
+
+		for _, difficultyRankings := range partitionParses {
+			unknown, ok := difficultyRankings[UnknownDifficulty]
+			if !ok {
+				continue
+			}
+
+			if difficultyRankings[difficulty] == nil {
+				difficultyRankings[difficulty] = SizeRankings{}
+			}
+			for size, metricRankings := range unknown {
+				if _, ok := difficultyRankings[difficulty][size]; !ok {
+					difficultyRankings[difficulty][size] = metricRankings
+				}
+			}
+			delete(difficultyRankings, UnknownDifficulty)
+			changed = true
+		}
+	}
+
+	return changed
 }
 
+// Partition is the WarcraftLogs rankings partition, a new one is usually started with each phase or season
+type Partition int
+
+// PartitionParses contains DifficultyRankings for multiple Partition
+type PartitionParses map[Partition]DifficultyRankings
+
+// DifficultyRankings contains SizeRankings for multiple Difficulty
+type DifficultyRankings map[Difficulty]SizeRankings
+
 // RaidSize is 10/25/40 raid group size
 type RaidSize int
 
@@ -28,22 +115,49 @@ type SizeRankings map[RaidSize]MetricRankings
 // Metric is either dps or hps
 type Metric string
 
+const (
+	MetricDPS Metric = "dps"
+	MetricHPS Metric = "hps"
+)
+
 func (m *Metric) Emoji() string {
-	switch string(*m) {
-	case "dps":
+	switch *m {
+	case MetricDPS:
 		return "<:dps:1052306073622675537>"
-	case "hps":
+	case MetricHPS:
 		return "<:heal:1052305955611746365>"
 	}
 	return ":question:"
 }
 
+func metricsContains(metrics []Metric, metric Metric) bool {
+	for _, m := range metrics {
+		if m == metric {
+			return true
+		}
+	}
+
+	return false
+}
+
 // MetricRankings contains Rankings for multiple Metric
 type MetricRankings map[Metric]PartitionRankings
 
+// Partition returns the Partition these rankings belong to, dps rankings are always queried so they take precedence
+func (m MetricRankings) Partition() Partition {
+	if rankings, ok := m[MetricDPS]; ok {
+		return rankings.Partition
+	}
+	for _, rankings := range m {
+		return rankings.Partition
+	}
+
+	return 0
+}
+
 // PartitionRankings contains a collection of Rankings for a specific Partition
 type PartitionRankings struct {
-	Partition int
+	Partition Partition
 	Rankings  []Ranking
 }
 
@@ -56,23 +170,28 @@ type Ranking struct {
 	RankPercent float64
 }
 
-// GetMetricRankingsForCharacter queries HPS and DPS ZoneParses for a specific Character, zone ID and raid size
-func (w *WCLogs) GetMetricRankingsForCharacter(char *Character, zoneID ZoneID, size RaidSize) (*MetricRankings, error) {
+// GetMetricRankingsForCharacter queries ZoneParses of each Metric for a specific Character, zone ID, difficulty and raid size
+func (w *WCLogs) GetMetricRankingsForCharacter(ctx context.Context, char *Character, zoneID ZoneID, difficulty Difficulty, size RaidSize, metrics []Metric) (*MetricRankings, error) {
 	req := graphql.NewRequest(`
-    query ($id: Int!, $zoneID: Int!, $size: Int!, $withHps: Boolean!) {
+    query ($id: Int!, $zoneID: Int!, $difficulty: Int!, $size: Int!, $withDps: Boolean!, $withHps: Boolean!) {
 		characterData {
 			character(id: $id) {
-				hpsZoneRankings: zoneRankings(metric: hps, zoneID: $zoneID, size: $size) @include(if: $withHps)
-				dpsZoneRankings: zoneRankings(metric: dps, zoneID: $zoneID, size: $size)
+				hpsZoneRankings: zoneRankings(metric: hps, zoneID: $zoneID, difficulty: $difficulty, size: $size) @include(if: $withHps)
+				dpsZoneRankings: zoneRankings(metric: dps, zoneID: $zoneID, difficulty: $difficulty, size: $size) @include(if: $withDps)
 			}
 		}
     }
 `)
 
+	withDps := metricsContains(metrics, MetricDPS)
+	withHps := metricsContains(metrics, MetricHPS)
+
 	req.Var("id", char.ID)
 	req.Var("zoneID", zoneID)
+	req.Var("difficulty", difficulty)
 	req.Var("size", size)
-	req.Var("withHps", char.CanHeal())
+	req.Var("withDps", withDps)
+	req.Var("withHps", withHps)
 
 	var resp struct {
 		CharacterData struct {
@@ -83,14 +202,16 @@ func (w *WCLogs) GetMetricRankingsForCharacter(char *Character, zoneID ZoneID, s
 		}
 	}
 
-	if err := w.client.Run(context.Background(), req, &resp); err != nil {
+	if err := w.run(ctx, "GetMetricRankingsForCharacter", req, &resp); err != nil {
 		return nil, err
 	}
 
 	metricRankings := make(MetricRankings)
-	metricRankings["dps"] = resp.CharacterData.Character.DpsZoneRankings
-	if char.CanHeal() {
-		metricRankings["hps"] = resp.CharacterData.Character.HpsZoneRankings
+	if withDps {
+		metricRankings[MetricDPS] = resp.CharacterData.Character.DpsZoneRankings
+	}
+	if withHps {
+		metricRankings[MetricHPS] = resp.CharacterData.Character.HpsZoneRankings
 	}
 
 	// HACK: Lower float resolution to help mitigate precision issues
@@ -103,17 +224,17 @@ func (w *WCLogs) GetMetricRankingsForCharacter(char *Character, zoneID ZoneID, s
 	return &metricRankings, nil
 }
 
-// GetParsesForCharacter queries all Parses for a specific Character
-func (w *WCLogs) GetParsesForCharacter(char *Character) (*Parses, error) {
+// GetParsesForCharacter queries all Parses for a specific Character, limited to metrics matching its roles
+func (w *WCLogs) GetParsesForCharacter(ctx context.Context, char *Character) (*Parses, error) {
 	var parses = make(Parses)
-	for _, zone := range cachedZones {
+	for _, zone := range getCachedZones() {
 		for _, difficulty := range zone.Difficulties {
 			for _, size := range difficulty.Sizes {
-				metricRankings, err := w.GetMetricRankingsForCharacter(char, zone.ID, size)
+				metricRankings, err := w.GetMetricRankingsForCharacter(ctx, char, zone.ID, difficulty.ID, size, char.Metrics())
 				if err != nil {
 					return nil, err
 				}
-				parses.MergeMetricRankings(zone.ID, size, metricRankings)
+				parses.MergeMetricRankings(zone.ID, difficulty.ID, size, metricRankings)
 			}
 		}
 	}
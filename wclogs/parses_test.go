@@ -0,0 +1,56 @@
+package wclogs
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestResolveUnknownDifficulty(t *testing.T) {
+	var zones Zones
+	err := json.Unmarshal([]byte(`[{"id":1020,"name":"Icecrown Citadel","difficulties":[{"id":3,"name":"Normal","sizes":[10,25]},{"id":4,"name":"Heroic","sizes":[10,25]}]}]`), &zones)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rankings := func(partition Partition, rankPercent float64) *MetricRankings {
+		ranking := Ranking{RankPercent: rankPercent}
+		ranking.Encounter.ID = 845
+		return &MetricRankings{"dps": {Partition: partition, Rankings: []Ranking{ranking}}}
+	}
+
+	parses := make(Parses)
+	parses.MergeMetricRankings(1020, UnknownDifficulty, 10, rankings(2, 50))
+	parses.MergeMetricRankings(1020, UnknownDifficulty, 25, rankings(2, 60))
+	parses.MergeMetricRankings(1020, 4, 25, rankings(2, 70))
+	// Unknown zones cannot be resolved
+	parses.MergeMetricRankings(1017, UnknownDifficulty, 25, rankings(1, 80))
+
+	if !parses.ResolveUnknownDifficulty(zones) {
+		t.Fatal("ResolveUnknownDifficulty = false, want parses changed")
+	}
+
+	tests := []struct {
+		zoneID      ZoneID
+		partition   Partition
+		difficulty  Difficulty
+		size        RaidSize
+		rankPercent float64
+	}{
+		{zoneID: 1020, partition: 2, difficulty: 4, size: 10, rankPercent: 50},
+		{zoneID: 1020, partition: 2, difficulty: 4, size: 25, rankPercent: 70},
+		{zoneID: 1017, partition: 1, difficulty: UnknownDifficulty, size: 25, rankPercent: 80},
+	}
+	for _, tt := range tests {
+		got := parses.Get(tt.zoneID, tt.partition, tt.difficulty, tt.size)
+		if got == nil || got["dps"].Rankings[0].RankPercent != tt.rankPercent {
+			t.Errorf("Get(%d, %d, %d) = %v, want RankPercent %v", tt.zoneID, tt.difficulty, tt.size, got, tt.rankPercent)
+		}
+	}
+	if parses.Get(1020, 2, UnknownDifficulty, 25) != nil {
+		t.Error("resolved rankings are still stored under UnknownDifficulty")
+	}
+
+	if parses.ResolveUnknownDifficulty(zones) {
+		t.Error("ResolveUnknownDifficulty = true on resolved parses")
+	}
+}
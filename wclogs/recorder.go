@@ -0,0 +1,148 @@
+package wclogs
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// fixture is a recorded WarcraftLogs API request and its response, saved as a JSON file
+type fixture struct {
+	Request      json.RawMessage `json:"request"`
+	Status       int             `json:"status"`
+	Response     json.RawMessage `json:"response,omitempty"`
+	ResponseText string          `json:"response_text,omitempty"`
+}
+
+// fixturePath returns the fixture file of a request body, named after its hash so that identical requests share it
+func fixturePath(dir string, body []byte) string {
+	sum := sha256.Sum256(body)
+	return filepath.Join(dir, hex.EncodeToString(sum[:8])+".json")
+}
+
+// readRequestBody reads and restores a request body
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	_ = req.Body.Close()
+	req.Body = io.NopCloser(bytes.NewReader(body))
+
+	return body, nil
+}
+
+// Recorder is an http.RoundTripper saving every request and response to fixture files, to be replayed by Replayer
+// Only request bodies are saved, authentication headers never are
+type Recorder struct {
+	dir  string
+	next http.RoundTripper
+}
+
+// NewRecorder returns a Recorder saving fixtures into dir, requests are sent through next
+func NewRecorder(dir string, next http.RoundTripper) *Recorder {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	return &Recorder{dir: dir, next: next}
+}
+
+// RoundTrip sends the request and saves the response before returning it
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	f := fixture{Request: body, Status: resp.StatusCode}
+	if !json.Valid(body) {
+		f.Request, _ = json.Marshal(string(body))
+	}
+	if json.Valid(respBody) {
+		f.Response = respBody
+	} else {
+		f.ResponseText = string(respBody)
+	}
+
+	out, err := json.MarshalIndent(&f, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err = os.MkdirAll(r.dir, 0o755); err != nil {
+		return nil, err
+	}
+	if err = os.WriteFile(fixturePath(r.dir, body), out, 0o644); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// Replayer is an http.RoundTripper answering requests from fixture files saved by Recorder, it never reaches the network
+type Replayer struct {
+	dir string
+}
+
+// NewReplayer returns a Replayer reading fixtures from dir
+func NewReplayer(dir string) *Replayer {
+	return &Replayer{dir: dir}
+}
+
+// RoundTrip returns the recorded response of an identical request, or an error if none was recorded
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	path := fixturePath(r.dir, body)
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("no fixture recorded for request: %w", err)
+	}
+
+	var f fixture
+	if err = json.Unmarshal(content, &f); err != nil {
+		return nil, fmt.Errorf("invalid fixture %s: %w", path, err)
+	}
+
+	respBody := []byte(f.Response)
+	if len(respBody) == 0 {
+		respBody = []byte(f.ResponseText)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", f.Status, http.StatusText(f.Status)),
+		StatusCode:    f.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(respBody)),
+		ContentLength: int64(len(respBody)),
+		Request:       req,
+	}, nil
+}
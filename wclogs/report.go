@@ -2,8 +2,9 @@ package wclogs
 
 import (
 	"context"
-	"errors"
+	"fmt"
 	"github.com/machinebox/graphql"
+	"strings"
 	"time"
 )
 
@@ -13,17 +14,125 @@ type ReportMetadata struct {
 	EndTime time.Time
 }
 
-// Report represents WarcraftLogs report including metadata and latest kill-fight
+// Report represents WarcraftLogs report including metadata and kill-fights
 type Report struct {
-	Code       string
-	EndTime    time.Time
+	Code    string
+	EndTime time.Time
+	// Raids contains every zone, difficulty and size combination killed in this report, empty until a first kill
+	Raids      []ReportRaid
+	Characters []int
+	Fights     []Fight
+}
+
+// ReportRaid is a zone, difficulty and raid size combination killed in a report, ranked separately by WarcraftLogs
+type ReportRaid struct {
 	ZoneID     ZoneID
+	Difficulty Difficulty
 	Size       RaidSize
-	Characters []int
+}
+
+// Fight represents a WarcraftLogs report kill-fight
+type Fight struct {
+	ID          int
+	EncounterID int
+	Difficulty  Difficulty
+	Size        RaidSize
+	// Specs contains the PlayerSpec played by each ranked character ID, only filled by GetReport
+	Specs map[int]PlayerSpec
+}
+
+// Raid returns the ReportRaid of a kill-fight, with a zero ZoneID if its encounter is not part of a relevant zone
+func (f *Fight) Raid() ReportRaid {
+	return ReportRaid{
+		ZoneID:     getCachedZones().GetZoneIDForEncounter(f.EncounterID),
+		Difficulty: f.Difficulty,
+		Size:       f.Size,
+	}
+}
+
+// LastFightForEncounter returns the latest kill-fight of an encounter in a raid of this report, or nil if not killed
+func (r *Report) LastFightForEncounter(raid ReportRaid, encounterID int) *Fight {
+	for idx := len(r.Fights) - 1; idx >= 0; idx-- {
+		fight := &r.Fights[idx]
+		if fight.EncounterID == encounterID && fight.Difficulty == raid.Difficulty && fight.Size == raid.Size {
+			return fight
+		}
+	}
+
+	return nil
+}
+
+// CharacterRaids returns the raids of this report where a character ID played a kill-fight
+// Every report raid is returned if the character specs are unknown
+func (r *Report) CharacterRaids(charID int) []ReportRaid {
+	raids := fightsRaids(r.Fights, func(fight *Fight) bool {
+		_, ok := fight.Specs[charID]
+		return ok
+	})
+	if len(raids) == 0 {
+		return r.Raids
+	}
+
+	return raids
+}
+
+// fightsRaids returns each distinct ReportRaid of matching fights in order of first kill, unknown zones are ignored
+func fightsRaids(fights []Fight, match func(fight *Fight) bool) []ReportRaid {
+	var raids []ReportRaid
+	for idx := range fights {
+		if !match(&fights[idx]) {
+			continue
+		}
+
+		raid := fights[idx].Raid()
+		if raid.ZoneID == 0 {
+			continue
+		}
+
+		known := false
+		for _, r := range raids {
+			if r == raid {
+				known = true
+			}
+		}
+		if !known {
+			raids = append(raids, raid)
+		}
+	}
+
+	return raids
+}
+
+// allFights matches every fight
+func allFights(*Fight) bool {
+	return true
+}
+
+// Roles returns every Role played by a character ID during the report kill-fights
+func (r *Report) Roles(charID int) []Role {
+	var roles []Role
+	for _, fight := range r.Fights {
+		spec, ok := fight.Specs[charID]
+		if !ok {
+			continue
+		}
+
+		known := false
+		for _, role := range roles {
+			if role == spec.Role {
+				known = true
+			}
+		}
+		if !known {
+			roles = append(roles, spec.Role)
+		}
+	}
+
+	return roles
 }
 
 // GetLatestReportMetadata queries latest Report for a specific Character
-func (w *WCLogs) GetLatestReportMetadata(char *Character) (*ReportMetadata, error) {
+func (w *WCLogs) GetLatestReportMetadata(ctx context.Context, char *Character) (*ReportMetadata, error) {
 	req := graphql.NewRequest(`
     query ($id: Int!) {
 		characterData {
@@ -54,12 +163,12 @@ func (w *WCLogs) GetLatestReportMetadata(char *Character) (*ReportMetadata, erro
 		}
 	}
 
-	if err := w.client.Run(context.Background(), req, &resp); err != nil {
+	if err := w.run(ctx, "GetLatestReportMetadata", req, &resp); err != nil {
 		return nil, err
 	}
 
 	if len(resp.CharacterData.Character.RecentReports.Data) < 1 {
-		return nil, errors.New("no recent report")
+		return nil, ErrNoRecentReport
 	}
 
 	report := &resp.CharacterData.Character.RecentReports.Data[0]
@@ -71,7 +180,7 @@ func (w *WCLogs) GetLatestReportMetadata(char *Character) (*ReportMetadata, erro
 }
 
 // GetLatestReport queries latest Report for a specific Character
-func (w *WCLogs) GetLatestReport(char *Character) (*Report, error) {
+func (w *WCLogs) GetLatestReport(ctx context.Context, char *Character) (*Report, error) {
 	req := graphql.NewRequest(`
     query ($id: Int!) {
 		characterData {
@@ -80,11 +189,10 @@ func (w *WCLogs) GetLatestReport(char *Character) (*Report, error) {
 					data {
 						code
 						endTime
-						zone {
-							id
-						}
 						fights(killType: Kills) {
+							id
 							encounterID
+							difficulty
 							size
 						}
 					}
@@ -103,57 +211,52 @@ func (w *WCLogs) GetLatestReport(char *Character) (*Report, error) {
 					Data []struct {
 						Code    string
 						EndTime float64
-						Zone    struct {
-							ID int
-						}
-						Fights []struct {
-							ID          int
-							EncounterID int
-							Size        int
-						}
+						Fights  []Fight
 					}
 				}
 			}
 		}
 	}
 
-	if err := w.client.Run(context.Background(), req, &resp); err != nil {
+	if err := w.run(ctx, "GetLatestReport", req, &resp); err != nil {
 		return nil, err
 	}
 
 	if len(resp.CharacterData.Character.RecentReports.Data) < 1 {
-		return nil, errors.New("no recent report")
+		return nil, ErrNoRecentReport
 	}
 
 	report := &resp.CharacterData.Character.RecentReports.Data[0]
-	lastFight := report.Fights[len(report.Fights)-1]
 
 	return &Report{
 		Code:    report.Code,
 		EndTime: time.UnixMilli(int64(report.EndTime)),
-		Size:    RaidSize(lastFight.Size),
-		ZoneID:  cachedZones.GetZoneIDForEncounter(lastFight.EncounterID),
+		Raids:   fightsRaids(report.Fights, allFights),
+		Fights:  report.Fights,
 	}, nil
 }
 
 // GetReport queries a specific report
-func (w *WCLogs) GetReport(reportCode string) (*Report, error) {
+func (w *WCLogs) GetReport(ctx context.Context, reportCode string) (*Report, error) {
 	req := graphql.NewRequest(`
     query ($code: String!) {
 		reportData {
 			report(code: $code) {
 				endTime
 				code
-				zone {
-					id
-				}
 				rankedCharacters {
 					id
+					name
+					server {
+						name
+						slug
+					}
 				}
 				fights(killType: Kills) {
 					id
 					encounterID
 					name
+					difficulty
 					size
 				}
 			}
@@ -166,39 +269,135 @@ func (w *WCLogs) GetReport(reportCode string) (*Report, error) {
 	var resp struct {
 		ReportData struct {
 			Report struct {
-				Code    string
-				EndTime float64
-				Zone    struct {
-					ID int
-				}
-				RankedCharacters []struct {
-					ID int
-				}
-				Fights []struct {
-					ID          int
-					EncounterID int
-					Size        int
-				}
+				Code             string
+				EndTime          float64
+				RankedCharacters []reportCharacter
+				Fights           []Fight
 			}
 		}
 	}
 
-	if err := w.client.Run(context.Background(), req, &resp); err != nil {
+	if err := w.run(ctx, "GetReport", req, &resp); err != nil {
 		return nil, err
 	}
 
 	report := resp.ReportData.Report
-	lastFight := report.Fights[len(report.Fights)-1]
 	var charIDs []int
 	for _, c := range report.RankedCharacters {
 		charIDs = append(charIDs, c.ID)
 	}
 
+	if err := w.fillFightsSpecs(ctx, reportCode, report.Fights, report.RankedCharacters); err != nil {
+		return nil, err
+	}
+
 	return &Report{
 		Code:       report.Code,
 		EndTime:    time.UnixMilli(int64(report.EndTime)),
-		Size:       RaidSize(lastFight.Size),
-		ZoneID:     cachedZones.GetZoneIDForEncounter(lastFight.EncounterID),
+		Raids:      fightsRaids(report.Fights, allFights),
 		Characters: charIDs,
+		Fights:     report.Fights,
 	}, nil
 }
+
+// reportCharacter represents a ranked character as listed in a report
+type reportCharacter struct {
+	ID     int
+	Name   string
+	Server struct {
+		Name string
+		Slug string
+	}
+}
+
+// matches returns true if reportCharacter is the player listed by name and server in report player details
+func (c *reportCharacter) matches(name, server string) bool {
+	if !strings.EqualFold(c.Name, name) {
+		return false
+	}
+
+	return server == "" || strings.EqualFold(c.Server.Name, server) || strings.EqualFold(c.Server.Slug, server)
+}
+
+// playerDetailsRoles maps report player details groups to a Role
+var playerDetailsRoles = map[string]Role{
+	"tanks":   RoleTank,
+	"healers": RoleHealer,
+	"dps":     RoleDPS,
+}
+
+// fillFightsSpecs queries player details of each fight to record the spec played by ranked characters
+func (w *WCLogs) fillFightsSpecs(ctx context.Context, reportCode string, fights []Fight, chars []reportCharacter) error {
+	if len(fights) == 0 {
+		return nil
+	}
+
+	// One aliased playerDetails per fight, so that roles are known for each fight in a single request
+	var fields []string
+	for _, fight := range fights {
+		fields = append(fields, fmt.Sprintf("fight%d: playerDetails(fightIDs: [%d])", fight.ID, fight.ID))
+	}
+
+	req := graphql.NewRequest(`
+    query ($code: String!) {
+		reportData {
+			report(code: $code) {
+				` + strings.Join(fields, "\n\t\t\t\t") + `
+			}
+		}
+    }
+`)
+
+	req.Var("code", reportCode)
+
+	var resp struct {
+		ReportData struct {
+			Report map[string]struct {
+				Data struct {
+					PlayerDetails map[string][]struct {
+						Name   string
+						Server string
+						Specs  []struct {
+							Spec string
+						}
+					}
+				}
+			}
+		}
+	}
+
+	if err := w.run(ctx, "fillFightsSpecs", req, &resp); err != nil {
+		return err
+	}
+
+	for idx := range fights {
+		details, ok := resp.ReportData.Report[fmt.Sprintf("fight%d", fights[idx].ID)]
+		if !ok {
+			continue
+		}
+
+		fights[idx].Specs = make(map[int]PlayerSpec)
+		for group, players := range details.Data.PlayerDetails {
+			role, ok := playerDetailsRoles[group]
+			if !ok {
+				continue
+			}
+
+			for _, player := range players {
+				for _, c := range chars {
+					if !c.matches(player.Name, player.Server) {
+						continue
+					}
+
+					spec := PlayerSpec{Role: role}
+					if len(player.Specs) > 0 {
+						spec.Spec = player.Specs[0].Spec
+					}
+					fights[idx].Specs[c.ID] = spec
+				}
+			}
+		}
+	}
+
+	return nil
+}
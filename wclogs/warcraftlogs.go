@@ -3,9 +3,12 @@ package wclogs
 
 import (
 	"context"
+	"errors"
 	"github.com/machinebox/graphql"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/clientcredentials"
+	"net/http"
+	"time"
 )
 
 //https://www.warcraftlogs.com/api/docs
@@ -18,12 +21,19 @@ const (
 
 const minSizeTrackedEncounter = 10
 
+// requestTimeout bounds each WarcraftLogs API request attempt, token requests included
+const requestTimeout = 30 * time.Second
+
 // WCLogs is the WarcraftLogs graphql API client holder
 type WCLogs struct {
-	client *graphql.Client
-	flavor Flavor
+	client   *graphql.Client
+	flavor   Flavor
+	observer RequestObserver
 }
 
+// RequestObserver is called after each WarcraftLogs API request with the query name, its duration and result
+type RequestObserver func(query string, duration time.Duration, err error)
+
 // Credentials represents WarcraftLogs credentials used to read from API
 type Credentials struct {
 	ClientID     string `json:"client_id"`
@@ -39,15 +49,20 @@ type RateLimitData struct {
 
 // New instantiates a new WCLogs graphql client
 func New(creds *Credentials, flavor Flavor, debugLogsFunc func(string)) *WCLogs {
-	c := clientcredentials.Config{
-		ClientID:     creds.ClientID,
-		ClientSecret: creds.ClientSecret,
-		TokenURL:     tokenUri,
-		AuthStyle:    oauth2.AuthStyleInHeader,
+	return NewWithHTTPClient(flavor, creds.HTTPClient(), debugLogsFunc)
+}
+
+// NewWithHTTPClient instantiates a new WCLogs graphql client sending requests through an already authenticated httpClient
+func NewWithHTTPClient(flavor Flavor, httpClient *http.Client, debugLogsFunc func(string)) *WCLogs {
+	// Copy the client so that error statuses are checked without altering the caller one
+	checkedClient := *httpClient
+	next := httpClient.Transport
+	if next == nil {
+		next = http.DefaultTransport
 	}
+	checkedClient.Transport = &statusTransport{next: next}
 
-	// TODO: check context value
-	client := graphql.NewClient(flavor.Uri(), graphql.WithHTTPClient(c.Client(context.Background())))
+	client := graphql.NewClient(flavor.Uri(), graphql.WithHTTPClient(&checkedClient))
 	if debugLogsFunc != nil {
 		client.Log = debugLogsFunc
 	}
@@ -57,24 +72,86 @@ func New(creds *Credentials, flavor Flavor, debugLogsFunc func(string)) *WCLogs
 	return &w
 }
 
-// Connect tries to connect to WarcraftLogs API, mostly used to validate credentials
-// TODO: it could be useful to also check rate limits here
-func (w *WCLogs) Connect() bool {
-	_, err := w.GetRateLimits()
-	if err != nil {
-		return false
+// HTTPClient returns an HTTP client authenticating requests with Credentials
+func (c *Credentials) HTTPClient() *http.Client {
+	config := clientcredentials.Config{
+		ClientID:     c.ClientID,
+		ClientSecret: c.ClientSecret,
+		TokenURL:     tokenUri,
+		AuthStyle:    oauth2.AuthStyleInHeader,
 	}
 
-	err = w.cacheZones()
+	// Tokens are fetched with this context HTTP client, outside of API requests context
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, &http.Client{Timeout: requestTimeout})
+	return config.Client(ctx)
+}
+
+// SetRequestObserver registers a RequestObserver called after each WarcraftLogs API request
+func (w *WCLogs) SetRequestObserver(observer RequestObserver) {
+	w.observer = observer
+}
+
+// run executes a named graphql request, decoding response into resp
+// Each attempt is bounded by requestTimeout, requests failing with ErrTransient are retried with a jittered exponential backoff
+func (w *WCLogs) run(ctx context.Context, query string, req *graphql.Request, resp interface{}) error {
+	for attempt := 0; ; attempt++ {
+		start := time.Now()
+		err := w.runAttempt(ctx, req, resp)
+		if w.observer != nil {
+			w.observer(query, time.Since(start), err)
+		}
+
+		if attempt >= maxRetries || !errors.Is(err, ErrTransient) {
+			return err
+		}
+
+		timer := time.NewTimer(retryDelay(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// MaxRequestDuration returns the longest a request may last, when every attempt times out after the longest retry delay
+func MaxRequestDuration() time.Duration {
+	duration := (maxRetries + 1) * requestTimeout
+	for attempt := 0; attempt < maxRetries; attempt++ {
+		duration += retryBaseDelay << attempt
+	}
+
+	return duration
+}
+
+// runAttempt executes a graphql request once, a timed out attempt is transient unless ctx is done
+func (w *WCLogs) runAttempt(ctx context.Context, req *graphql.Request, resp interface{}) error {
+	attemptCtx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	err := classifyError(w.client.Run(attemptCtx, req, resp))
+	if err != nil && ctx.Err() == nil && attemptCtx.Err() == context.DeadlineExceeded {
+		return &classifiedError{kind: ErrTransient, cause: err}
+	}
+
+	return err
+}
+
+// Connect tries to connect to WarcraftLogs API, mostly used to validate credentials
+// Rejected credentials fail with ErrUnauthorized
+// TODO: it could be useful to also check rate limits here
+func (w *WCLogs) Connect(ctx context.Context) error {
+	_, err := w.GetRateLimits(ctx)
 	if err != nil {
-		return false
+		return err
 	}
 
-	return true
+	return w.cacheZones(ctx)
 }
 
 // GetRateLimits queries RateLimitData from WarcraftLogs API
-func (w *WCLogs) GetRateLimits() (*RateLimitData, error) {
+func (w *WCLogs) GetRateLimits(ctx context.Context) (*RateLimitData, error) {
 	req := graphql.NewRequest(`
     query {
         rateLimitData {
@@ -89,7 +166,7 @@ func (w *WCLogs) GetRateLimits() (*RateLimitData, error) {
 		RateLimitData RateLimitData
 	}
 
-	if err := w.client.Run(context.Background(), req, &resp); err != nil {
+	if err := w.run(ctx, "GetRateLimits", req, &resp); err != nil {
 		return nil, err
 	}
 
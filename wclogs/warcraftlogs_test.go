@@ -0,0 +1,174 @@
+package wclogs
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fixturesDir contains responses saved by Recorder, such as with `epa check --record`
+const fixturesDir = "testdata/classic"
+
+// newReplayWCLogs returns a connected WCLogs answering requests from recorded fixtures
+func newReplayWCLogs(t *testing.T) *WCLogs {
+	t.Helper()
+	w := NewWithHTTPClient(Classic, &http.Client{Transport: NewReplayer(fixturesDir)}, nil)
+	if err := w.Connect(context.Background()); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+
+	return w
+}
+
+func TestConnectCachesRelevantZones(t *testing.T) {
+	w := newReplayWCLogs(t)
+
+	if zone := w.GetZone(1020); zone == nil || zone.Name != "Icecrown Citadel" {
+		t.Errorf("GetZone(1020) = %v, want Icecrown Citadel", zone)
+	}
+	if zone := w.GetZone(1021); zone != nil {
+		t.Errorf("GetZone(1021) = %v, want nil as dungeons are not relevant", zone)
+	}
+}
+
+func TestGetCharacter(t *testing.T) {
+	w := newReplayWCLogs(t)
+
+	tests := []struct {
+		name    string
+		want    *Character
+		wantErr error
+	}{
+		{
+			name: "Foo",
+			want: &Character{ID: 12345678, Name: "Foo", Server: "Gehennas", Region: "EU", ClassID: 2},
+		},
+		{
+			name:    "Unknown",
+			wantErr: ErrCharacterNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := w.GetCharacter(context.Background(), tt.name, "gehennas", "EU")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GetCharacter error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetCharacter = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetLatestReportMetadata(t *testing.T) {
+	w := newReplayWCLogs(t)
+
+	tests := []struct {
+		name    string
+		charID  int
+		want    *ReportMetadata
+		wantErr error
+	}{
+		{
+			name:   "recent report",
+			charID: 12345678,
+			want:   &ReportMetadata{Code: "aBcD1234", EndTime: time.UnixMilli(1700000000000)},
+		},
+		{
+			name:    "no report",
+			charID:  87654321,
+			wantErr: ErrNoRecentReport,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := w.GetLatestReportMetadata(context.Background(), &Character{ID: tt.charID})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GetLatestReportMetadata error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetLatestReportMetadata = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetReport(t *testing.T) {
+	w := newReplayWCLogs(t)
+
+	t.Run("raids", func(t *testing.T) {
+		report, err := w.GetReport(context.Background(), "aBcD1234")
+		if err != nil {
+			t.Fatalf("GetReport failed: %v", err)
+		}
+
+		wantRaids := []ReportRaid{{1020, 3, 10}, {1020, 3, 25}, {1020, 4, 25}}
+		if !reflect.DeepEqual(report.Raids, wantRaids) {
+			t.Errorf("Raids = %v, want %v", report.Raids, wantRaids)
+		}
+		if want := []int{12345678, 23456789}; !reflect.DeepEqual(report.Characters, want) {
+			t.Errorf("Characters = %v, want %v", report.Characters, want)
+		}
+		if want := []Role{RoleHealer, RoleDPS}; !reflect.DeepEqual(report.Roles(12345678), want) {
+			t.Errorf("Roles = %v, want %v", report.Roles(12345678), want)
+		}
+		if want := wantRaids[:2]; !reflect.DeepEqual(report.CharacterRaids(23456789), want) {
+			t.Errorf("CharacterRaids = %v, want %v", report.CharacterRaids(23456789), want)
+		}
+		if fight := report.LastFightForEncounter(ReportRaid{1020, 3, 25}, 845); fight == nil || fight.ID != 5 {
+			t.Errorf("LastFightForEncounter = %v, want fight 5", fight)
+		}
+	})
+
+	t.Run("no kills", func(t *testing.T) {
+		report, err := w.GetReport(context.Background(), "NoKills01")
+		if err != nil {
+			t.Fatalf("GetReport failed: %v", err)
+		}
+
+		if len(report.Raids) != 0 || len(report.Fights) != 0 {
+			t.Errorf("Raids = %v, Fights = %v, want none", report.Raids, report.Fights)
+		}
+	})
+}
+
+func TestGetMetricRankingsForCharacter(t *testing.T) {
+	w := newReplayWCLogs(t)
+
+	rankings, err := w.GetMetricRankingsForCharacter(context.Background(), &Character{ID: 12345678}, 1020, 3, 25, []Metric{MetricDPS})
+	if err != nil {
+		t.Fatalf("GetMetricRankingsForCharacter failed: %v", err)
+	}
+
+	if _, ok := (*rankings)[MetricHPS]; ok {
+		t.Error("hps rankings returned, only dps was queried")
+	}
+	if partition := rankings.Partition(); partition != 2 {
+		t.Errorf("Partition = %d, want 2", partition)
+	}
+
+	dps := (*rankings)[MetricDPS].Rankings
+	if len(dps) != 2 {
+		t.Fatalf("got %d rankings, want 2", len(dps))
+	}
+	if dps[0].Encounter.ID != 845 || dps[0].RankPercent != 87.457 {
+		t.Errorf("first ranking = %+v, want rounded 87.457 on encounter 845", dps[0])
+	}
+	if dps[1].RankPercent != 0 {
+		t.Errorf("unkilled encounter RankPercent = %v, want 0", dps[1].RankPercent)
+	}
+}
+
+func TestReplayerUnknownRequest(t *testing.T) {
+	w := newReplayWCLogs(t)
+
+	if _, err := w.GetReport(context.Background(), "Unknown"); err == nil {
+		t.Error("GetReport succeeded without recorded fixture")
+	}
+}
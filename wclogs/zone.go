@@ -3,14 +3,19 @@ package wclogs
 import (
 	"context"
 	"github.com/machinebox/graphql"
+	"strconv"
+	"sync"
 )
 
+// Difficulty represents the raid difficulty identifier, such as normal or heroic
+type Difficulty int
+
 // Zone represents a WoW zone
 type Zone struct {
 	ID           ZoneID
 	Name         string
 	Difficulties []struct {
-		ID    int
+		ID    Difficulty
 		Name  string
 		Sizes []RaidSize
 	}
@@ -32,6 +37,35 @@ func (z Zone) IsRelevant() bool {
 	return false
 }
 
+// DifficultyName returns printable Difficulty for this zone
+func (z *Zone) DifficultyName(difficulty Difficulty) string {
+	if z != nil {
+		for _, d := range z.Difficulties {
+			if d.ID == difficulty {
+				return d.Name
+			}
+		}
+	}
+
+	return strconv.Itoa(int(difficulty))
+}
+
+// HighestDifficulty returns the highest Difficulty of this zone, used by WarcraftLogs when none is queried
+func (z *Zone) HighestDifficulty() (Difficulty, bool) {
+	if z == nil || len(z.Difficulties) == 0 {
+		return UnknownDifficulty, false
+	}
+
+	highest := z.Difficulties[0].ID
+	for _, d := range z.Difficulties[1:] {
+		if d.ID > highest {
+			highest = d.ID
+		}
+	}
+
+	return highest, true
+}
+
 type Zones []Zone
 
 func (z Zones) GetZoneIDForEncounter(encounterID int) ZoneID {
@@ -46,10 +80,43 @@ func (z Zones) GetZoneIDForEncounter(encounterID int) ZoneID {
 	return 0
 }
 
-var cachedZones Zones
+// GetZone returns a cached Zone, or nil if unknown
+func (z Zones) GetZone(zoneID ZoneID) *Zone {
+	for idx := range z {
+		if z[idx].ID == zoneID {
+			return &z[idx]
+		}
+	}
+
+	return nil
+}
+
+// cachedZones contains the relevant zones of the current expansion, fetched by the first successful Connect
+var cachedZones struct {
+	sync.RWMutex
+	zones Zones
+}
+
+// getCachedZones returns cached zones, nil until fetched
+func getCachedZones() Zones {
+	cachedZones.RLock()
+	defer cachedZones.RUnlock()
+
+	return cachedZones.zones
+}
+
+// Zones returns cached relevant zones of the current expansion, nil until connected
+func (w *WCLogs) Zones() Zones {
+	return getCachedZones()
+}
+
+// GetZone returns a cached Zone from the current expansion, or nil if unknown
+func (w *WCLogs) GetZone(zoneID ZoneID) *Zone {
+	return getCachedZones().GetZone(zoneID)
+}
 
 // getZones queries a collection of Zone, this is static data for each expansion
-func (w *WCLogs) getZones() (Zones, error) {
+func (w *WCLogs) getZones(ctx context.Context) (Zones, error) {
 	req := graphql.NewRequest(`
     query ($expansion: Int!) {
 		worldData {
@@ -77,7 +144,7 @@ func (w *WCLogs) getZones() (Zones, error) {
 		}
 	}
 
-	if err := w.client.Run(context.Background(), req, &resp); err != nil {
+	if err := w.run(ctx, "getZones", req, &resp); err != nil {
 		return nil, err
 	}
 
@@ -91,12 +158,18 @@ func (w *WCLogs) getZones() (Zones, error) {
 	return zones, nil
 }
 
-func (w *WCLogs) cacheZones() error {
-	if cachedZones != nil {
+func (w *WCLogs) cacheZones(ctx context.Context) error {
+	if getCachedZones() != nil {
 		return nil
 	}
 
-	var err error
-	cachedZones, err = w.getZones()
-	return err
+	zones, err := w.getZones(ctx)
+	if err != nil {
+		return err
+	}
+
+	cachedZones.Lock()
+	defer cachedZones.Unlock()
+	cachedZones.zones = zones
+	return nil
 }
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/zergrael/epa/wclogs"
+)
+
+// TestGuildWCLogsConcurrentDestroy is meant for go test -race, handlers read instances while tickers destroy them
+func TestGuildWCLogsConcurrentDestroy(t *testing.T) {
+	previousLogs := logs
+	logs = make(map[string]*wclogs.WCLogs)
+	t.Cleanup(func() { logs = previousLogs })
+
+	const guildID = "1"
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for n := 0; n < 100; n++ {
+			setGuildWCLogs(guildID, wclogs.New(&wclogs.Credentials{}, wclogs.Classic, nil))
+			destroyWCLogsForGuild(guildID)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for n := 0; n < 100; n++ {
+			_ = guildWCLogs(guildID)
+			_ = wclogsContext(guildID)
+		}
+	}()
+	wg.Wait()
+
+	if guildWCLogs(guildID) != nil {
+		t.Error("guildWCLogs returned a destroyed instance")
+	}
+	if wclogsContext(guildID) != requestsCtx {
+		t.Error("wclogsContext returned a destroyed instance context")
+	}
+}
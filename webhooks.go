@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/rs/zerolog/log"
+	"github.com/zergrael/epa/wclogs"
+)
+
+// webhookName is the default name of webhooks created by the bot
+const webhookName = "EPA announcements"
+
+// classIconURL is the class icon link used as webhook avatar, formatted with the lowercase class name without spaces
+const classIconURL = "https://wow.zamimg.com/images/wow/icons/large/classicon_%s.jpg"
+
+// classAvatarURL returns the class icon link of a character, empty if the class is not known
+func classAvatarURL(char *wclogs.Character) string {
+	className := char.ClassName()
+	if className == "Unknown" {
+		return ""
+	}
+
+	return fmt.Sprintf(classIconURL, strings.ToLower(strings.ReplaceAll(className, " ", "")))
+}
+
+// channelWebhook returns the bot webhook of a channel, it is created and stored on first use
+func channelWebhook(guildID, channelID string) (*ChannelWebhook, error) {
+	webhook, err := fetchChannelWebhook(db, guildID, channelID)
+	if err != nil {
+		return nil, err
+	}
+	if webhook != nil {
+		return webhook, nil
+	}
+
+	created, err := s.WebhookCreate(channelID, webhookName, "")
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info().Str("guildID", guildID).Str("channelID", channelID).Str("webhookID", created.ID).Msg("Created channel webhook")
+	webhook = &ChannelWebhook{ID: created.ID, Token: created.Token}
+	return webhook, storeChannelWebhook(db, guildID, channelID, webhook)
+}
+
+// sendWebhookAnnouncement sends a message through a channel webhook, it is created again once if deleted meanwhile
+func sendWebhookAnnouncement(guildID, channelID string, params *discordgo.WebhookParams) error {
+	for attempt := 0; ; attempt++ {
+		webhook, err := channelWebhook(guildID, channelID)
+		if err != nil {
+			return err
+		}
+
+		_, err = s.WebhookExecute(webhook.ID, webhook.Token, false, params)
+		if attempt > 0 || !isDiscordError(err, discordgo.ErrCodeUnknownWebhook) {
+			return err
+		}
+
+		log.Warn().Str("guildID", guildID).Str("channelID", channelID).Str("webhookID", webhook.ID).Msg("Channel webhook was deleted")
+		if err := deleteChannelWebhook(db, guildID, channelID); err != nil {
+			return err
+		}
+	}
+}
+
+// isDiscordError returns true if err is a Discord API error with one of codes
+func isDiscordError(err error, codes ...int) bool {
+	var restErr *discordgo.RESTError
+	if !errors.As(err, &restErr) || restErr.Message == nil {
+		return false
+	}
+
+	for _, code := range codes {
+		if restErr.Message.Code == code {
+			return true
+		}
+	}
+
+	return false
+}